@@ -13,8 +13,13 @@ import (
 
 	"github.com/faisal/crypto/backend/internal/config"
 	"github.com/faisal/crypto/backend/internal/handlers"
+	"github.com/faisal/crypto/backend/internal/services/alerts"
+	"github.com/faisal/crypto/backend/internal/services/auth"
+	"github.com/faisal/crypto/backend/internal/services/catalog"
 	"github.com/faisal/crypto/backend/internal/services/market"
 	"github.com/faisal/crypto/backend/internal/services/portfolio"
+	"github.com/faisal/crypto/backend/internal/services/pricehistory"
+	"github.com/faisal/crypto/backend/internal/services/watchlist"
 )
 
 func main() {
@@ -32,11 +37,47 @@ func main() {
 	marketHandler := handlers.NewMarketHandler(marketService)
 	marketHandler.Register(api)
 
+	catalogService := catalog.NewService(cfg, marketService)
+	coinHandler := handlers.NewCoinHandler(catalogService)
+	coinHandler.Register(api)
+
 	// Using in-memory storage for development
 	// TODO: Switch to MongoDB when connection is ready
-	portfolioService := portfolio.NewService(cfg)
+	authService := auth.NewService(cfg)
+	authHandler := handlers.NewAuthHandler(authService)
+	authHandler.Register(api)
+
+	// Everything below acts on behalf of the authenticated user, signed in
+	// with a password session or a personal API key
+	authenticated := api.Group("", config.AuthMiddleware(authService))
+	keyHandler := handlers.NewKeyHandler(authService)
+	keyHandler.Register(authenticated)
+
+	// Reads need portfolio:read and writes holdings:write
+	protected := authenticated.Group("", config.ScopeMiddleware())
+
+	priceHistoryService := pricehistory.NewService(cfg, marketService)
+	priceHandler := handlers.NewPriceHandler(priceHistoryService)
+	priceHandler.Register(protected)
+
+	portfolioService := portfolio.NewService(cfg, marketService, priceHistoryService, catalogService)
 	portfolioHandler := handlers.NewPortfolioHandler(portfolioService)
-	portfolioHandler.Register(api)
+	portfolioHandler.Register(protected)
+	reportHandler := handlers.NewReportHandler(portfolioService)
+	reportHandler.Register(protected)
+
+	alertService := alerts.NewService(cfg, marketService, portfolioService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	alertHandler.Register(protected)
+
+	watchlistService := watchlist.NewService(cfg, marketService)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistService)
+	watchlistHandler.Register(protected)
+
+	// Start polling once every listener has subscribed to market updates
+	marketService.Start()
+	catalogService.Start()
+	portfolioService.StartSnapshots()
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -60,6 +101,18 @@ func main() {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		log.Fatalf("server forced to shutdown: %v", err)
 	}
+	if err := portfolioService.StopSnapshots(ctxShutdown); err != nil {
+		log.Printf("snapshot scheduler did not stop cleanly: %v", err)
+	}
+	if err := catalogService.Stop(ctxShutdown); err != nil {
+		log.Printf("coin catalog sync did not stop cleanly: %v", err)
+	}
+	if err := marketService.Stop(ctxShutdown); err != nil {
+		log.Printf("market poller did not stop cleanly: %v", err)
+	}
+	if err := alertService.Wait(ctxShutdown); err != nil {
+		log.Printf("alert evaluation did not finish: %v", err)
+	}
 
 	log.Println("Server exiting")
 }
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -8,6 +12,8 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/models"
 )
 
 type Config struct {
@@ -17,10 +23,68 @@ type Config struct {
 
 	CoinGeckoBaseURL string
 	CoinGeckoAPIKey  string
+	CoinCapBaseURL   string
+	CoinCapAPIKey    string
+
+	// MarketProviders lists upstream sources in failover priority order
+	MarketProviders         []string
+	ProviderCooldownSeconds int
 
 	CacheTTLSeconds int
 	MarketDataLimit int // Number of coins to fetch (for dev/testing)
 	AllowedOrigins  []string
+
+	// MarketPollIntervalSeconds schedules the background refresher; 0 disables it
+	MarketPollIntervalSeconds   int
+	MarketPollMaxBackoffSeconds int
+
+	HistoryCacheTTLSeconds int
+	OHLCCacheTTLSeconds    int
+
+	// PriceLookupToleranceHours is how old a stored price may be and still
+	// value a holding at a past date
+	PriceLookupToleranceHours int
+
+	DefaultCostBasisMethod string // fifo, lifo, hifo or average
+
+	// RiskFreeRatePercent is the annual rate Sharpe and Sortino ratios are
+	// measured against
+	RiskFreeRatePercent float64
+
+	// SnapshotCheckIntervalSeconds is how often the scheduler looks for due
+	// portfolio snapshots; 0 disables it
+	SnapshotCheckIntervalSeconds int
+	DefaultSnapshotFrequency     string // hourly, daily or off
+	// SnapshotTolerancePercent is how far a client-reported total may differ
+	// from the server's valuation before the snapshot is rejected
+	SnapshotTolerancePercent int
+	// SnapshotMinIntervalSeconds is the shortest gap allowed between a
+	// portfolio's latest snapshot and a client-requested one
+	SnapshotMinIntervalSeconds int
+
+	// AlertCooldownMinutes is the default quiet period for cooldown alerts
+	AlertCooldownMinutes       int
+	AlertWebhookTimeoutSeconds int
+
+	// CoinCatalogSyncHours is how often the local coin catalog is refreshed
+	// from the upstream coin list; 0 only loads what is already stored
+	CoinCatalogSyncHours int
+
+	// TaxJurisdiction is the default for tax reports. TaxLongTermDays
+	// overrides a jurisdiction's long-term holding period, keyed by code;
+	// 0 drops the short/long distinction.
+	TaxJurisdiction string
+	TaxLongTermDays map[string]int
+
+	DefaultCurrency      string
+	SupportedCurrencies  []string
+	MarketPollCurrencies []string // Currencies the poller keeps warm
+
+	JWTSecret             string
+	AccessTokenTTLMinutes int
+	RefreshTokenTTLHours  int
+	// AdminEmails may run global operations such as price backfills
+	AdminEmails []string
 }
 
 func Load() (*Config, error) {
@@ -38,13 +102,82 @@ func Load() (*Config, error) {
 		MongoDBName:      getEnv("MONGO_DB_NAME", "crypto"),
 		CoinGeckoBaseURL: getEnv("COINGECKO_BASE_URL", "https://api.coingecko.com/api/v3"),
 		CoinGeckoAPIKey:  getEnv("COINGECKO_API_KEY", ""),
+		CoinCapBaseURL:   getEnv("COINCAP_BASE_URL", "https://api.coincap.io/v2"),
+		CoinCapAPIKey:    getEnv("COINCAP_API_KEY", ""),
 		CacheTTLSeconds:  cacheTTL,
 		MarketDataLimit:  marketLimit,
 		AllowedOrigins:   origin,
+
+		MarketPollIntervalSeconds:   getEnvAsInt("MARKET_POLL_INTERVAL_SECONDS", 60),
+		MarketPollMaxBackoffSeconds: getEnvAsInt("MARKET_POLL_MAX_BACKOFF_SECONDS", 300),
+
+		HistoryCacheTTLSeconds: getEnvAsInt("HISTORY_CACHE_TTL_SECONDS", 600),
+		OHLCCacheTTLSeconds:    getEnvAsInt("OHLC_CACHE_TTL_SECONDS", 900),
+
+		PriceLookupToleranceHours: getEnvAsInt("PRICE_LOOKUP_TOLERANCE_HOURS", 48),
+
+		MarketProviders:         getEnvAsList("MARKET_PROVIDERS", "coingecko,coincap"),
+		ProviderCooldownSeconds: getEnvAsInt("PROVIDER_COOLDOWN_SECONDS", 60),
+
+		DefaultCostBasisMethod: strings.ToLower(strings.TrimSpace(getEnv("COST_BASIS_METHOD", "fifo"))),
+
+		RiskFreeRatePercent: getEnvAsFloat("RISK_FREE_RATE_PERCENT", 4),
+
+		SnapshotCheckIntervalSeconds: getEnvAsInt("SNAPSHOT_CHECK_INTERVAL_SECONDS", 300),
+		DefaultSnapshotFrequency:     strings.ToLower(strings.TrimSpace(getEnv("SNAPSHOT_FREQUENCY", "daily"))),
+		SnapshotTolerancePercent:     getEnvAsInt("SNAPSHOT_TOLERANCE_PERCENT", 2),
+		SnapshotMinIntervalSeconds:   getEnvAsInt("SNAPSHOT_MIN_INTERVAL_SECONDS", 60),
+
+		AlertCooldownMinutes:       getEnvAsInt("ALERT_COOLDOWN_MINUTES", 60),
+		AlertWebhookTimeoutSeconds: getEnvAsInt("ALERT_WEBHOOK_TIMEOUT_SECONDS", 5),
+
+		CoinCatalogSyncHours: getEnvAsInt("COIN_CATALOG_SYNC_HOURS", 24),
+
+		TaxJurisdiction: strings.ToLower(strings.TrimSpace(getEnv("TAX_JURISDICTION", "us"))),
+		TaxLongTermDays: getEnvAsIntMap("TAX_LONG_TERM_DAYS"),
+
+		DefaultCurrency:      strings.ToLower(getEnv("DEFAULT_CURRENCY", "usd")),
+		SupportedCurrencies:  getEnvAsList("SUPPORTED_CURRENCIES", "usd,eur,inr,gbp,jpy"),
+		MarketPollCurrencies: getEnvAsList("MARKET_POLL_CURRENCIES", "usd"),
+
+		JWTSecret:             getEnv("JWT_SECRET", ""),
+		AccessTokenTTLMinutes: getEnvAsInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLHours:  getEnvAsInt("REFRESH_TOKEN_TTL_HOURS", 720),
+		AdminEmails:           getEnvAsList("ADMIN_EMAILS", ""),
+	}
+	if cfg.JWTSecret == "" {
+		return nil, errors.New("JWT_SECRET must be set")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
+// validate rejects defaults that would otherwise only fail once a request
+// falls back to them
+func (c *Config) validate() error {
+	if !models.ValidCostBasisMethod(c.DefaultCostBasisMethod) {
+		return fmt.Errorf("COST_BASIS_METHOD must be fifo, lifo, hifo or average, got %q", c.DefaultCostBasisMethod)
+	}
+	if _, ok := models.SnapshotInterval(c.DefaultSnapshotFrequency); !ok && c.DefaultSnapshotFrequency != models.SnapshotOff {
+		return fmt.Errorf("SNAPSHOT_FREQUENCY must be hourly, daily or off, got %q", c.DefaultSnapshotFrequency)
+	}
+	if c.MarketPollIntervalSeconds > 0 && c.MarketPollMaxBackoffSeconds < c.MarketPollIntervalSeconds {
+		return fmt.Errorf("MARKET_POLL_MAX_BACKOFF_SECONDS must be at least MARKET_POLL_INTERVAL_SECONDS (%d), got %d", c.MarketPollIntervalSeconds, c.MarketPollMaxBackoffSeconds)
+	}
+	codes := strings.Join(models.TaxJurisdictionCodes(), ", ")
+	if _, ok := models.LookupTaxJurisdiction(c.TaxJurisdiction); !ok {
+		return fmt.Errorf("TAX_JURISDICTION must be one of %s, got %q", codes, c.TaxJurisdiction)
+	}
+	for code := range c.TaxLongTermDays {
+		if _, ok := models.LookupTaxJurisdiction(code); !ok {
+			return fmt.Errorf("TAX_LONG_TERM_DAYS must only name %s, got %q", codes, code)
+		}
+	}
+	return nil
+}
+
 func getEnv(key string, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -60,6 +193,41 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+func getEnvAsFloat(key string, fallback float64) float64 {
+	valStr := getEnv(key, "")
+	if val, err := strconv.ParseFloat(valStr, 64); err == nil {
+		return val
+	}
+	return fallback
+}
+
+// getEnvAsList splits a comma-separated value into trimmed, lower-case entries
+func getEnvAsList(key string, fallback string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsIntMap reads "key=value" pairs such as "us=365,de=365" into a map
+// with lower-case keys, skipping malformed entries
+func getEnvAsIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, item := range getEnvAsList(key, "") {
+		name, raw, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if val, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil {
+			values[strings.ToLower(strings.TrimSpace(name))] = val
+		}
+	}
+	return values
+}
+
 func CORSMiddleware(origins []string) gin.HandlerFunc {
 	allowed := make(map[string]struct{}, len(origins))
 	for _, origin := range origins {
@@ -70,8 +238,9 @@ func CORSMiddleware(origins []string) gin.HandlerFunc {
 		origin := c.Request.Header.Get("Origin")
 		if _, ok := allowed[origin]; ok {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-API-Key, If-Match")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Age, X-Currency, X-Data-Status, X-Data-Partial, X-Data-Fetched-At, X-Next-Cursor")
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
 
@@ -83,3 +252,91 @@ func CORSMiddleware(origins []string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// userIDKey is the gin context key holding the authenticated user's ID
+const userIDKey = "userID"
+
+// principalKey is the gin context key holding the full *models.Principal
+const principalKey = "principal"
+
+// Authenticator resolves a bearer access token or personal API key
+type Authenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*models.Principal, error)
+}
+
+// AuthMiddleware rejects requests without a valid bearer access token or
+// personal API key and records the caller on the context for CurrentUserID.
+// API keys are accepted as a bearer token or in the X-API-Key header.
+func AuthMiddleware(authn Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-API-Key")
+		if token == "" {
+			token, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token or api key"})
+			return
+		}
+		principal, err := authn.AuthenticateToken(c.Request.Context(), token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(userIDKey, principal.UserID)
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// ScopeMiddleware applies the read scope to safe methods and the write scope
+// to everything else. It must run after AuthMiddleware.
+func ScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := models.ScopeHoldingsWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = models.ScopePortfolioRead
+		}
+		if p := CurrentPrincipal(c); p == nil || !p.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+// KeyManagementMiddleware lets password sessions through and requires the
+// admin scope from API keys, so a leaked read key cannot mint new keys
+func KeyManagementMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := CurrentPrincipal(c)
+		if p == nil || (p.APIKeyID != "" && !p.HasScope(models.ScopeAdmin)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + models.ScopeAdmin})
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminMiddleware restricts global operations to users on ADMIN_EMAILS
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := CurrentPrincipal(c); p == nil || !p.Admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentPrincipal returns the caller set by AuthMiddleware, or nil
+func CurrentPrincipal(c *gin.Context) *models.Principal {
+	p, _ := c.Get(principalKey)
+	principal, _ := p.(*models.Principal)
+	return principal
+}
+
+// CurrentUserID returns the user ID set by AuthMiddleware
+func CurrentUserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
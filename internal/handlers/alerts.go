@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/alerts"
+)
+
+const (
+	defaultAlertListLimit = 100
+	maxAlertListLimit     = 1000
+)
+
+type AlertHandler struct {
+	service *alerts.Service
+}
+
+func NewAlertHandler(service *alerts.Service) *AlertHandler {
+	return &AlertHandler{service: service}
+}
+
+func (h *AlertHandler) Register(router *gin.RouterGroup) {
+	rules := router.Group("/alerts")
+	rules.GET("", h.listRules)
+	rules.POST("", h.createRule)
+	rules.GET("/events", h.listEvents)
+	rules.GET("/:id", h.getRule)
+	rules.PUT("/:id", h.updateRule)
+	rules.DELETE("/:id", h.deleteRule)
+
+	inbox := router.Group("/inbox")
+	inbox.GET("", h.listInbox)
+	inbox.POST("/:id/read", h.markRead)
+}
+
+type alertRuleRequest struct {
+	Name            string                `json:"name"`
+	Condition       models.AlertCondition `json:"condition" binding:"required"`
+	CoinID          string                `json:"coinId"`
+	PortfolioID     string                `json:"portfolioId"`
+	Threshold       float64               `json:"threshold"`
+	Currency        string                `json:"currency"`
+	Mode            models.AlertMode      `json:"mode"`
+	CooldownMinutes int                   `json:"cooldownMinutes"`
+	Channels        []string              `json:"channels"`
+	WebhookURL      string                `json:"webhookUrl"`
+	Enabled         *bool                 `json:"enabled"` // defaults to true
+}
+
+func (r alertRuleRequest) rule(userID string) models.AlertRule {
+	enabled := r.Enabled == nil || *r.Enabled
+	return models.AlertRule{
+		UserID:          userID,
+		Name:            r.Name,
+		Condition:       r.Condition,
+		CoinID:          r.CoinID,
+		PortfolioID:     r.PortfolioID,
+		Threshold:       r.Threshold,
+		Currency:        r.Currency,
+		Mode:            r.Mode,
+		CooldownMinutes: r.CooldownMinutes,
+		Channels:        r.Channels,
+		WebhookURL:      r.WebhookURL,
+		Enabled:         enabled,
+	}
+}
+
+func (h *AlertHandler) listRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *AlertHandler) createRule(c *gin.Context) {
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule, err := h.service.CreateRule(c.Request.Context(), req.rule(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *AlertHandler) getRule(c *gin.Context) {
+	rule, err := h.service.GetRule(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if errors.Is(err, alerts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *AlertHandler) updateRule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": alerts.ErrNotFound.Error()})
+		return
+	}
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule := req.rule(currentUserID(c))
+	rule.ID = id
+	updated, err := h.service.UpdateRule(c.Request.Context(), rule)
+	if errors.Is(err, alerts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *AlertHandler) deleteRule(c *gin.Context) {
+	err := h.service.DeleteRule(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if errors.Is(err, alerts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listEvents returns the fired-alert history, optionally for one ?ruleId=
+func (h *AlertHandler) listEvents(c *gin.Context) {
+	limit, err := parseListLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.service.ListEvents(c.Request.Context(), currentUserID(c), c.Query("ruleId"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// listInbox returns in-app notifications newest first; ?unread=true hides read ones
+func (h *AlertHandler) listInbox(c *gin.Context) {
+	limit, err := parseListLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+	msgs, err := h.service.ListInbox(c.Request.Context(), currentUserID(c), unreadOnly, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, msgs)
+}
+
+func (h *AlertHandler) markRead(c *gin.Context) {
+	err := h.service.MarkInboxRead(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if errors.Is(err, alerts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseListLimit(c *gin.Context) (int, error) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultAlertListLimit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 || limit > maxAlertListLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxAlertListLimit)
+	}
+	return limit, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/services/catalog"
+)
+
+const (
+	defaultCoinSearchLimit = 10
+	maxCoinSearchLimit     = 50
+)
+
+type CoinHandler struct {
+	service *catalog.Service
+}
+
+func NewCoinHandler(service *catalog.Service) *CoinHandler {
+	return &CoinHandler{service: service}
+}
+
+func (h *CoinHandler) Register(router *gin.RouterGroup) {
+	router.GET("/coins/search", h.search)
+	router.GET("/coins/:id", h.getCoin)
+}
+
+// search finds catalog coins by ID, symbol or name, tolerating typos
+func (h *CoinHandler) search(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := defaultCoinSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCoinSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if h.service.Count() == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": catalog.ErrCatalogUnavailable.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.service.Search(q, limit))
+}
+
+func (h *CoinHandler) getCoin(c *gin.Context) {
+	coin, err := h.service.Get(c.Param("id"))
+	if errors.Is(err, catalog.ErrUnknownCoin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, coin)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/services/market"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/export"
+)
+
+// exportPortfolio streams ?dataset=holdings|transactions|snapshots as
+// ?format=csv|jsonl|excel, with amounts converted into ?currency. from and
+// to (RFC3339, to exclusive) bound transactions and snapshots; holdings are
+// valued as of to.
+func (h *PortfolioHandler) exportPortfolio(c *gin.Context) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := portfolio.ExportQuery{Dataset: portfolio.ExportDataset(c.DefaultQuery("dataset", string(portfolio.ExportTransactions)))}
+	if !query.Dataset.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": portfolio.ErrInvalidDataset.Error()})
+		return
+	}
+	for name, dst := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s, expected RFC3339", name)})
+				return
+			}
+			*dst = t
+		}
+	}
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	query.Currency, err = h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("%s-%s-%s.%s", portfolioID, query.Dataset, time.Now().UTC().Format("20060102"), export.Extension(format))
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Currency", query.Currency)
+
+	err = h.service.Export(c.Request.Context(), userID, portfolioID, query, w)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// The status is already sent; cutting the stream short is all that
+		// is left
+		log.Printf("export %s for %s: %v", query.Dataset, userID, err)
+		c.Abort()
+		return
+	}
+	// c.JSON keeps an existing Content-Type, so drop the file headers
+	// before reporting the error
+	for _, name := range []string{"Content-Type", "Content-Disposition", "X-Currency"} {
+		c.Writer.Header().Del(name)
+	}
+	status := http.StatusInternalServerError
+	if errors.Is(err, market.ErrUnsupportedCurrency) || errors.Is(err, market.ErrRatesUnsupported) {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
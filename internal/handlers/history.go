@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+const (
+	defaultHistoryLimit = 1000
+	maxHistoryLimit     = 10000
+)
+
+// encodeCursor renders a keyset position as an opaque token
+func encodeCursor(cursor *portfolio.SnapshotCursor) string {
+	raw := strconv.FormatInt(int64(cursor.Timestamp), 10)
+	if !cursor.ID.IsZero() {
+		raw += ":" + cursor.ID.Hex()
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(token string) (*portfolio.SnapshotCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	ms, id, hasID := strings.Cut(string(raw), ":")
+	millis, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	cursor := &portfolio.SnapshotCursor{Timestamp: primitive.DateTime(millis)}
+	if hasID {
+		if cursor.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+	return cursor, nil
+}
+
+// parseSnapshotQuery reads from, to, limit, order and cursor
+func parseSnapshotQuery(c *gin.Context) (portfolio.SnapshotQuery, error) {
+	query := portfolio.SnapshotQuery{Limit: defaultHistoryLimit}
+	for name, dst := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s, expected RFC3339", name)
+			}
+			*dst = t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+		query.Limit = limit
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return query, err
+		}
+		query.After = cursor
+	}
+	return query, nil
+}
+
+// getHistory returns snapshots in timestamp order, one page at a time; the
+// X-Next-Cursor header carries the token for the next page. ?bucket=1h|1d|1w
+// downsamples totals into OHLC buckets per currency, and ?detail=assets adds
+// each snapshot's per-asset breakdown plus a time series per currency and coin.
+func (h *PortfolioHandler) getHistory(c *gin.Context) {
+	query, err := parseSnapshotQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	detail := c.DefaultQuery("detail", "totals")
+	if detail != "totals" && detail != "assets" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "detail must be totals or assets"})
+		return
+	}
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+
+	if bucket := c.Query("bucket"); bucket != "" {
+		if detail != "totals" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucket only supports detail=totals"})
+			return
+		}
+		buckets, next, err := h.service.SnapshotBuckets(c.Request.Context(), userID, portfolioID, query, bucket)
+		if errors.Is(err, portfolio.ErrInvalidBucket) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		setNextCursor(c, next)
+		c.JSON(http.StatusOK, buckets)
+		return
+	}
+
+	data, next, err := h.service.ListSnapshots(c.Request.Context(), userID, portfolioID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	setNextCursor(c, next)
+	if detail == "assets" {
+		c.JSON(http.StatusOK, gin.H{
+			"snapshots": data,
+			"series":    portfolio.AssetSeries(data),
+		})
+		return
+	}
+	for i := range data {
+		data[i].Assets = nil
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func setNextCursor(c *gin.Context, next *portfolio.SnapshotCursor) {
+	if next != nil {
+		c.Header("X-Next-Cursor", encodeCursor(next))
+	}
+}
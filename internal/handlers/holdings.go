@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+// holdingETag is a strong entity tag derived from the holding's version
+func holdingETag(holding *models.Holding) string {
+	return strconv.Quote(strconv.FormatInt(holding.Version, 10))
+}
+
+// parseIfMatch reads the version a client last saw from If-Match. A "*"
+// matches any current version and is reported with wildcard set.
+func parseIfMatch(header string) (version int64, wildcard bool, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return 0, true, true
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, false
+	}
+	version, err = strconv.ParseInt(unquoted, 10, 64)
+	return version, false, err == nil
+}
+
+func (h *PortfolioHandler) getHolding(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	holding, err := h.service.GetHolding(c.Request.Context(), id, currentUserID(c), currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", holdingETag(holding))
+	c.JSON(http.StatusOK, holding)
+}
+
+// updateHoldingRequest fields left out keep their current value
+type updateHoldingRequest struct {
+	CoinID *string  `json:"coinId"`
+	Amount *float64 `json:"amount"`
+}
+
+// updateHolding applies a partial update guarded by If-Match. Writes based on
+// a stale version get 409 Conflict; a missing If-Match gets 428.
+func (h *PortfolioHandler) updateHolding(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+	version, wildcard, ok := parseIfMatch(ifMatch)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header"})
+		return
+	}
+	var req updateHoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	holding, err := h.service.GetHolding(c.Request.Context(), id, currentUserID(c), currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !wildcard {
+		holding.Version = version
+	}
+	if req.CoinID != nil {
+		holding.CoinID = *req.CoinID
+	}
+	if req.Amount != nil {
+		holding.Amount = *req.Amount
+	}
+
+	res, err := h.service.UpdateHolding(c.Request.Context(), *holding)
+	if errors.Is(err, portfolio.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "holding was modified by another request"})
+		return
+	}
+	if errors.Is(err, portfolio.ErrCoinInLedger) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, portfolio.ErrCatalogUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", holdingETag(res))
+	c.JSON(http.StatusOK, res)
+}
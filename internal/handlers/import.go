@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/importer"
+)
+
+// maxImportBytes caps an uploaded CSV
+const maxImportBytes = 5 << 20
+
+// importRecords accepts a CSV as a multipart "file" field or as the raw
+// request body. Options come from form fields or the query string: format,
+// mapping and symbols (both JSON), skipErrors, and commit; without
+// commit=true the import is only previewed.
+func (h *PortfolioHandler) importRecords(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	multipart := c.ContentType() == gin.MIMEMultipartPOSTForm
+	option := func(name string) string {
+		if multipart {
+			if value, ok := c.GetPostForm(name); ok {
+				return value
+			}
+		}
+		return c.Query(name)
+	}
+
+	var body io.Reader = c.Request.Body
+	if multipart {
+		file, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file exceeds 5MB"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	opts := portfolio.ImportOptions{Format: option("format")}
+	if raw := option("mapping"); raw != "" {
+		opts.Mapping = &importer.Mapping{}
+		if err := json.Unmarshal([]byte(raw), opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping: " + err.Error()})
+			return
+		}
+	}
+	if raw := option("symbols"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Symbols); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid symbols: " + err.Error()})
+			return
+		}
+	}
+	for name, target := range map[string]*bool{"commit": &opts.Commit, "skipErrors": &opts.SkipErrors} {
+		if raw := option(name); raw != "" {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+				return
+			}
+			*target = value
+		}
+	}
+
+	preview, err := h.service.Import(c.Request.Context(), currentUserID(c), currentPortfolioID(c), body, opts)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file exceeds 5MB"})
+	case errors.Is(err, portfolio.ErrImportHasErrors), errors.Is(err, portfolio.ErrInsufficientBalance):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "preview": preview})
+	case errors.Is(err, portfolio.ErrCatalogUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, importer.ErrUnknownFormat), errors.Is(err, importer.ErrUnrecognizedFormat),
+		errors.Is(err, importer.ErrTooManyRows):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case preview.Committed:
+		c.JSON(http.StatusCreated, preview)
+	default:
+		c.JSON(http.StatusOK, preview)
+	}
+}
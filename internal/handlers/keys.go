@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/auth"
+)
+
+type KeyHandler struct {
+	service *auth.Service
+}
+
+func NewKeyHandler(service *auth.Service) *KeyHandler {
+	return &KeyHandler{service: service}
+}
+
+// Register mounts key management on an authenticated group. Password
+// sessions may manage keys; API keys need the admin scope.
+func (h *KeyHandler) Register(router *gin.RouterGroup) {
+	keys := router.Group("/keys", config.KeyManagementMiddleware())
+	keys.GET("", h.listKeys)
+	keys.POST("", h.createKey)
+	keys.DELETE("/:id", h.revokeKey)
+}
+
+type createKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (h *KeyHandler) listKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *KeyHandler) createKey(c *gin.Context) {
+	var req createKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, plaintext, err := h.service.CreateAPIKey(c.Request.Context(), currentUserID(c), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The plaintext key is only ever shown in this response
+	c.JSON(http.StatusCreated, gin.H{
+		"key":    key,
+		"secret": plaintext,
+	})
+}
+
+func (h *KeyHandler) revokeKey(c *gin.Context) {
+	err := h.service.RevokeAPIKey(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
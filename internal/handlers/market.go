@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -18,13 +21,99 @@ func NewMarketHandler(service *market.Service) *MarketHandler {
 
 func (h *MarketHandler) Register(router *gin.RouterGroup) {
 	router.GET("/market", h.getMarket)
+	router.GET("/market/status", h.getStatus)
+	router.GET("/market/:coinId/history", h.getHistory)
+	router.GET("/market/:coinId/ohlc", h.getOHLC)
+	router.GET("/market/:coinId/risk", h.getRisk)
 }
 
 func (h *MarketHandler) getMarket(c *gin.Context) {
-	data, err := h.service.GetTopMarketData()
+	data, err := h.service.GetMarketData(c.Query("currency"))
+	if errors.Is(err, market.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
+	setFreshnessHeaders(c, data)
+	c.Header("X-Currency", data.Currency)
+	c.JSON(http.StatusOK, data.Coins)
+}
+
+// setFreshnessHeaders tells clients whether the payload is fresh or a stale
+// copy served while the upstream is being retried, and flags payloads from a
+// failover provider whose rows lack sparklines
+func setFreshnessHeaders(c *gin.Context, data *market.MarketData) {
+	status := "fresh"
+	if data.Stale {
+		status = "stale"
+	}
+	c.Header("X-Data-Status", status)
+	if data.Partial {
+		c.Header("X-Data-Partial", "true")
+	}
+	c.Header("X-Data-Fetched-At", data.FetchedAt.UTC().Format(time.RFC3339))
+	c.Header("Age", strconv.Itoa(int(data.Age().Seconds())))
+}
+
+func (h *MarketHandler) getStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.PollerStatus())
+}
+
+func (h *MarketHandler) getHistory(c *gin.Context) {
+	currency, err := h.service.NormalizeCurrency(c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.GetHistory(c.Param("coinId"), currency, c.Query("range"), c.Query("granularity"))
+	if err != nil {
+		c.JSON(historyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Currency", currency)
 	c.JSON(http.StatusOK, data)
 }
+
+func (h *MarketHandler) getOHLC(c *gin.Context) {
+	currency, err := h.service.NormalizeCurrency(c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.GetOHLC(c.Param("coinId"), currency, c.Query("range"), c.Query("granularity"))
+	if err != nil {
+		c.JSON(historyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Currency", currency)
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *MarketHandler) getRisk(c *gin.Context) {
+	currency, err := h.service.NormalizeCurrency(c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.GetCoinRisk(c.Param("coinId"), currency, c.Query("range"))
+	if err != nil {
+		c.JSON(historyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func historyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, market.ErrInvalidRange), errors.Is(err, market.ErrInvalidGranularity):
+		return http.StatusBadRequest
+	case errors.Is(err, market.ErrCoinNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, market.ErrHistoryUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusBadGateway
+}
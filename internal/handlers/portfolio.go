@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -19,34 +20,91 @@ func NewPortfolioHandler(service *portfolio.Service) *PortfolioHandler {
 	return &PortfolioHandler{service: service}
 }
 
+// Register mounts the portfolio routes twice: /portfolio acts on the user's
+// default portfolio and /portfolios/:pid on any of their portfolios.
 func (h *PortfolioHandler) Register(router *gin.RouterGroup) {
-	router.GET("/portfolio", h.getPortfolio)
-	router.POST("/portfolio", h.createHolding)
-	router.DELETE("/portfolio/:id", h.deleteHolding)
+	router.GET("/portfolio/settings", h.getSettings)
+	router.PUT("/portfolio/settings", h.updateSettings)
+	registerPortfolioRoutes(router.Group("/portfolio"), h)
 
-	router.GET("/portfolio/history", h.getHistory)
-	router.POST("/portfolio/history", h.createSnapshot)
+	router.GET("/portfolios", h.listPortfolios)
+	router.POST("/portfolios", h.createPortfolio)
+	router.GET("/portfolios/aggregate", h.getAggregate)
+	router.PUT("/portfolios/:pid", h.requirePortfolio, h.updatePortfolio)
+	router.DELETE("/portfolios/:pid", h.requirePortfolio, h.deletePortfolio)
+	registerPortfolioRoutes(router.Group("/portfolios/:pid", h.requirePortfolio), h)
+}
+
+func registerPortfolioRoutes(router *gin.RouterGroup, h *PortfolioHandler) {
+	router.GET("", h.getPortfolio)
+	router.POST("", h.createHolding)
+	router.POST("/import", h.importRecords)
+	router.GET("/export", h.exportPortfolio)
+	router.GET("/:id", h.getHolding)
+	router.PATCH("/:id", h.updateHolding)
+	router.DELETE("/:id", h.deleteHolding)
+
+	router.GET("/history", h.getHistory)
+	router.POST("/history", h.createSnapshot)
+
+	router.GET("/transactions", h.listTransactions)
+	router.POST("/transactions", h.createTransaction)
+	router.GET("/transactions/:id", h.getTransaction)
+	router.PUT("/transactions/:id", h.updateTransaction)
+	router.DELETE("/transactions/:id", h.deleteTransaction)
+
+	router.GET("/lots", h.getLots)
+	router.GET("/performance", h.getPerformance)
+	router.GET("/risk", h.getRisk)
+
+	router.GET("/targets", h.getTargets)
+	router.PUT("/targets", h.updateTargets)
+	router.GET("/rebalance", h.getRebalance)
 }
 
 type createHoldingRequest struct {
-	UserID string  `json:"userId" binding:"required"`
 	CoinID string  `json:"coinId" binding:"required"`
 	Amount float64 `json:"amount" binding:"required"`
 }
 
 func (h *PortfolioHandler) getPortfolio(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		userID = "1"
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if atParam := c.Query("at"); atParam != "" {
+		at, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at, expected RFC3339"})
+			return
+		}
+		data, total, err := h.service.GetHoldingsWithValueAt(c.Request.Context(), userID, portfolioID, at, currency)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"portfolioId": portfolioID,
+			"asOf":        at.UTC(),
+			"currency":    currency,
+			"totalValue":  total,
+			"holdings":    data,
+		})
+		return
 	}
-	data, total, err := h.service.GetHoldingsWithValue(c.Request.Context(), userID)
+	data, total, err := h.service.GetHoldingsWithValue(c.Request.Context(), userID, portfolioID, currency)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"totalValue": total,
-		"holdings":   data,
+		"portfolioId": portfolioID,
+		"currency":    currency,
+		"totalValue":  total,
+		"holdings":    data,
 	})
 }
 
@@ -57,41 +115,80 @@ func (h *PortfolioHandler) createHolding(c *gin.Context) {
 		return
 	}
 	holding := models.Holding{
-		UserID: req.UserID,
-		CoinID: req.CoinID,
-		Amount: req.Amount,
+		UserID:      currentUserID(c),
+		PortfolioID: currentPortfolioID(c),
+		CoinID:      req.CoinID,
+		Amount:      req.Amount,
 	}
 	res, err := h.service.CreateHolding(c.Request.Context(), holding)
+	if errors.Is(err, portfolio.ErrCoinInLedger) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, portfolio.ErrCatalogUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", holdingETag(res))
 	c.JSON(http.StatusCreated, res)
 }
 
 func (h *PortfolioHandler) deleteHolding(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		userID = "1"
-	}
+	userID := currentUserID(c)
 	id := c.Param("id")
 	if _, err := primitive.ObjectIDFromHex(id); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.service.DeleteHolding(c.Request.Context(), id, userID); err != nil {
+	if err := h.service.DeleteHolding(c.Request.Context(), id, userID, currentPortfolioID(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
-func (h *PortfolioHandler) getHistory(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		userID = "1"
+// createSnapshotRequest may carry the total the client computed; it is
+// checked against the server's valuation but never stored
+type createSnapshotRequest struct {
+	TotalValue *float64 `json:"totalValue"`
+}
+
+func (h *PortfolioHandler) createSnapshot(c *gin.Context) {
+	var req createSnapshotRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	res, err := h.service.TakeSnapshot(c.Request.Context(), currentUserID(c), currentPortfolioID(c), models.SnapshotSourceClient, req.TotalValue)
+	if errors.Is(err, portfolio.ErrSnapshotTooSoon) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	var mismatch *portfolio.SnapshotMismatchError
+	if errors.As(err, &mismatch) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         err.Error(),
+			"computedTotal": mismatch.Computed,
+			"currency":      mismatch.Currency,
+		})
+		return
 	}
-	data, err := h.service.ListSnapshots(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *PortfolioHandler) getSettings(c *gin.Context) {
+	userID := currentUserID(c)
+	data, err := h.service.GetSettings(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -99,26 +196,94 @@ func (h *PortfolioHandler) getHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, data)
 }
 
-type createSnapshotRequest struct {
-	UserID     string  `json:"userId" binding:"required"`
-	TotalValue float64 `json:"totalValue" binding:"required"`
+// updateSettingsRequest fields left empty keep their current value
+type updateSettingsRequest struct {
+	CostBasisMethod   string `json:"costBasisMethod"`
+	Currency          string `json:"currency"`
+	SnapshotFrequency string `json:"snapshotFrequency"`
 }
 
-func (h *PortfolioHandler) createSnapshot(c *gin.Context) {
-	var req createSnapshotRequest
+func (h *PortfolioHandler) updateSettings(c *gin.Context) {
+	var req updateSettingsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	snapshot := models.Snapshot{
-		UserID:     req.UserID,
-		TotalValue: req.TotalValue,
-		Timestamp:  models.ToPrimitiveDateTime(time.Now()),
+	settings, err := h.service.GetSettings(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CostBasisMethod != "" {
+		settings.CostBasisMethod = req.CostBasisMethod
 	}
-	res, err := h.service.CreateSnapshot(c.Request.Context(), snapshot)
+	if req.Currency != "" {
+		settings.Currency = req.Currency
+	}
+	if req.SnapshotFrequency != "" {
+		settings.SnapshotFrequency = req.SnapshotFrequency
+	}
+	res, err := h.service.UpdateSettings(c.Request.Context(), *settings)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, res)
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *PortfolioHandler) getLots(c *gin.Context) {
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.CostBasis(c.Request.Context(), userID, portfolioID, currency)
+	if errors.Is(err, portfolio.ErrOversold) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"currency":  currency,
+		"method":    data.Method,
+		"positions": data.Positions,
+		"disposals": data.Disposals,
+	})
+}
+
+func (h *PortfolioHandler) getPerformance(c *gin.Context) {
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.Performance(c.Request.Context(), userID, portfolioID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *PortfolioHandler) getRisk(c *gin.Context) {
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.Risk(c.Request.Context(), userID, portfolioID, currency, c.Query("range"))
+	if err != nil {
+		c.JSON(historyErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
 }
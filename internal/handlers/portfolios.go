@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+// currentPortfolioID returns the :pid route parameter, or the default
+// portfolio on the legacy /portfolio routes
+func currentPortfolioID(c *gin.Context) string {
+	return models.PortfolioOf(c.Param("pid"))
+}
+
+// requirePortfolio answers 404 unless :pid names one of the user's portfolios
+func (h *PortfolioHandler) requirePortfolio(c *gin.Context) {
+	_, err := h.service.GetPortfolio(c.Request.Context(), currentUserID(c), currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "portfolio not found"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Next()
+}
+
+type portfolioRequest struct {
+	Name            string `json:"name" binding:"required"`
+	BaseCurrency    string `json:"baseCurrency"`
+	CostBasisMethod string `json:"costBasisMethod"`
+}
+
+func (r portfolioRequest) toModel(userID string) models.Portfolio {
+	return models.Portfolio{
+		UserID:          userID,
+		Name:            r.Name,
+		BaseCurrency:    r.BaseCurrency,
+		CostBasisMethod: r.CostBasisMethod,
+	}
+}
+
+func (h *PortfolioHandler) listPortfolios(c *gin.Context) {
+	data, err := h.service.ListPortfolios(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *PortfolioHandler) createPortfolio(c *gin.Context) {
+	var req portfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.service.CreatePortfolio(c.Request.Context(), req.toModel(currentUserID(c)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *PortfolioHandler) updatePortfolio(c *gin.Context) {
+	var req portfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p := req.toModel(currentUserID(c))
+	p.ID = currentPortfolioID(c)
+	res, err := h.service.UpdatePortfolio(c.Request.Context(), p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *PortfolioHandler) deletePortfolio(c *gin.Context) {
+	err := h.service.DeletePortfolio(c.Request.Context(), currentUserID(c), currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrDefaultPortfolio) || errors.Is(err, portfolio.ErrPortfolioNotEmpty) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// getAggregate values every portfolio in ?currency=, defaulting to the
+// user's default currency rather than any portfolio's base currency
+func (h *PortfolioHandler) getAggregate(c *gin.Context) {
+	userID := currentUserID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, "", c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := h.service.GetAggregate(c.Request.Context(), userID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/services/pricehistory"
+)
+
+type PriceHandler struct {
+	service *pricehistory.Service
+}
+
+func NewPriceHandler(service *pricehistory.Service) *PriceHandler {
+	return &PriceHandler{service: service}
+}
+
+func (h *PriceHandler) Register(router *gin.RouterGroup) {
+	router.GET("/prices/:coinId", h.getPrices)
+	// Backfills fill the shared store from the upstream API
+	router.POST("/prices/backfill", config.AdminMiddleware(), h.startBackfill)
+	router.GET("/prices/backfill/:id", h.getBackfill)
+}
+
+// getPrices answers a point lookup with ?at= or lists stored records with ?from=&to=
+func (h *PriceHandler) getPrices(c *gin.Context) {
+	coinID := c.Param("coinId")
+	if atParam := c.Query("at"); atParam != "" {
+		at, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at, expected RFC3339"})
+			return
+		}
+		record, err := h.service.PriceAt(c.Request.Context(), coinID, at)
+		if errors.Is(err, pricehistory.ErrNoPrice) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, record)
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, expected RFC3339"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, expected RFC3339"})
+			return
+		}
+	}
+	data, err := h.service.ListPrices(c.Request.Context(), coinID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+type backfillRequest struct {
+	CoinID string    `json:"coinId" binding:"required"`
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required"`
+}
+
+func (h *PriceHandler) startBackfill(c *gin.Context) {
+	var req backfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job, err := h.service.StartBackfill(req.CoinID, req.From, req.To)
+	if errors.Is(err, pricehistory.ErrBackfillBusy) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *PriceHandler) getBackfill(c *gin.Context) {
+	job, err := h.service.GetBackfill(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+func (h *PortfolioHandler) getTargets(c *gin.Context) {
+	p, err := h.service.GetPortfolio(c.Request.Context(), currentUserID(c), currentPortfolioID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"targets":               p.Targets,
+		"driftTolerancePercent": p.DriftTolerancePercent,
+	})
+}
+
+type targetsRequest struct {
+	Targets               []models.TargetWeight `json:"targets"`
+	DriftTolerancePercent float64               `json:"driftTolerancePercent"`
+}
+
+func (h *PortfolioHandler) updateTargets(c *gin.Context) {
+	var req targetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.SetTargets(c.Request.Context(), currentUserID(c), currentPortfolioID(c), req.Targets, req.DriftTolerancePercent)
+	if errors.Is(err, portfolio.ErrCatalogUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"targets":               p.Targets,
+		"driftTolerancePercent": p.DriftTolerancePercent,
+	})
+}
+
+// getRebalance plans trades back to the target allocation. ?minTrade= is in
+// the response currency and ?feePercent= estimates fees per trade.
+func (h *PortfolioHandler) getRebalance(c *gin.Context) {
+	userID := currentUserID(c)
+	portfolioID := currentPortfolioID(c)
+	currency, err := h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var minTrade, feePercent float64
+	for name, dst := range map[string]*float64{"minTrade": &minTrade, "feePercent": &feePercent} {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+				return
+			}
+			*dst = f
+		}
+	}
+	plan, err := h.service.Rebalance(c.Request.Context(), userID, portfolioID, currency, minTrade, feePercent)
+	if errors.Is(err, portfolio.ErrNoTargets) || errors.Is(err, portfolio.ErrNothingToRebalance) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
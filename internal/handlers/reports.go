@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/export"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/tax"
+)
+
+type ReportHandler struct {
+	service *portfolio.Service
+}
+
+func NewReportHandler(service *portfolio.Service) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+func (h *ReportHandler) Register(router *gin.RouterGroup) {
+	router.GET("/reports/tax", h.getTaxReport)
+}
+
+// getTaxReport reports ?portfolio (default portfolio when omitted) for
+// ?year, the calendar year the tax year starts in, under ?jurisdiction and
+// ?method. ?format=csv returns the lines of ?section=disposals|income
+// instead of the JSON report.
+func (h *ReportHandler) getTaxReport(c *gin.Context) {
+	userID := currentUserID(c)
+	portfolioID := models.PortfolioOf(c.Query("portfolio"))
+	if _, err := h.service.GetPortfolio(c.Request.Context(), userID, portfolioID); errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "portfolio not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := portfolio.TaxQuery{Method: c.Query("method"), Jurisdiction: c.Query("jurisdiction")}
+	if v := c.Query("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil || year < 1970 || year > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+		query.Year = year
+	}
+	format := c.DefaultQuery("format", "json")
+	section := c.DefaultQuery("section", "disposals")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+	if section != "disposals" && section != "income" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "section must be disposals or income"})
+		return
+	}
+	var err error
+	query.Currency, err = h.service.ResolveCurrency(c.Request.Context(), userID, portfolioID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.TaxReport(c.Request.Context(), userID, portfolioID, query)
+	switch {
+	case errors.Is(err, tax.ErrUnknownJurisdiction), errors.Is(err, portfolio.ErrInvalidCostBasisMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, portfolio.ErrTaxCurrencyMismatch), errors.Is(err, portfolio.ErrOversold):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Currency", report.Currency)
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	w, err := export.NewWriter(export.FormatCSV, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", export.ContentType(export.FormatCSV))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("tax-%s-%d-%s.csv", report.Jurisdiction.Code, report.Year, section)))
+	if section == "income" {
+		err = report.WriteIncome(w)
+	} else {
+		err = report.WriteDisposals(w)
+	}
+	if err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+type transactionRequest struct {
+	CoinID        string                 `json:"coinId" binding:"required"`
+	Type          models.TransactionType `json:"type" binding:"required"`
+	Quantity      float64                `json:"quantity" binding:"required"`
+	UnitPrice     float64                `json:"unitPrice"`
+	QuoteCurrency string                 `json:"quoteCurrency"`
+	Timestamp     *time.Time             `json:"timestamp"`
+	Notes         string                 `json:"notes"`
+	IncomeType    string                 `json:"incomeType"`
+}
+
+func (r transactionRequest) toModel(userID string, portfolioID string) models.Transaction {
+	tx := models.Transaction{
+		UserID:        userID,
+		PortfolioID:   portfolioID,
+		CoinID:        r.CoinID,
+		Type:          r.Type,
+		Quantity:      r.Quantity,
+		UnitPrice:     r.UnitPrice,
+		QuoteCurrency: r.QuoteCurrency,
+		Notes:         r.Notes,
+		IncomeType:    r.IncomeType,
+	}
+	if r.Timestamp != nil {
+		tx.Timestamp = models.ToPrimitiveDateTime(*r.Timestamp)
+	}
+	return tx
+}
+
+func (h *PortfolioHandler) listTransactions(c *gin.Context) {
+	userID := currentUserID(c)
+	data, err := h.service.ListTransactions(c.Request.Context(), userID, currentPortfolioID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *PortfolioHandler) getTransaction(c *gin.Context) {
+	userID := currentUserID(c)
+	id := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	tx, err := h.service.GetTransaction(c.Request.Context(), id, userID, currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tx)
+}
+
+func (h *PortfolioHandler) createTransaction(c *gin.Context) {
+	var req transactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.service.CreateTransaction(c.Request.Context(), req.toModel(currentUserID(c), currentPortfolioID(c)))
+	if errors.Is(err, portfolio.ErrCatalogUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *PortfolioHandler) updateTransaction(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req transactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tx := req.toModel(currentUserID(c), currentPortfolioID(c))
+	tx.ID = objID
+	res, err := h.service.UpdateTransaction(c.Request.Context(), tx)
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, portfolio.ErrCatalogUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *PortfolioHandler) deleteTransaction(c *gin.Context) {
+	userID := currentUserID(c)
+	id := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	err := h.service.DeleteTransaction(c.Request.Context(), id, userID, currentPortfolioID(c))
+	if errors.Is(err, portfolio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, portfolio.ErrInsufficientBalance) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/market"
+	"github.com/faisal/crypto/backend/internal/services/watchlist"
+)
+
+type WatchlistHandler struct {
+	service *watchlist.Service
+}
+
+func NewWatchlistHandler(service *watchlist.Service) *WatchlistHandler {
+	return &WatchlistHandler{service: service}
+}
+
+func (h *WatchlistHandler) Register(router *gin.RouterGroup) {
+	lists := router.Group("/watchlists")
+	lists.GET("", h.listWatchlists)
+	lists.POST("", h.createWatchlist)
+	lists.GET("/:id", h.getWatchlist)
+	lists.PUT("/:id", h.updateWatchlist)
+	lists.DELETE("/:id", h.deleteWatchlist)
+	lists.POST("/:id/items", h.addItem)
+	lists.PATCH("/:id/items/:coinId", h.updateItem)
+	lists.DELETE("/:id/items/:coinId", h.removeItem)
+}
+
+type watchlistRequest struct {
+	Name  string                 `json:"name" binding:"required"`
+	Items []models.WatchlistItem `json:"items"`
+}
+
+type watchlistItemRequest struct {
+	CoinID   string  `json:"coinId"`
+	Note     *string `json:"note"`
+	Position *int    `json:"position"` // zero-based; omitted appends or keeps the current place
+}
+
+// watchlistErrorStatus maps service errors to HTTP statuses
+func watchlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, watchlist.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, watchlist.ErrDuplicateCoin), errors.Is(err, watchlist.ErrTooManyItems):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// listWatchlists returns every watchlist with live market data in ?currency=
+func (h *WatchlistHandler) listWatchlists(c *gin.Context) {
+	views, err := h.service.ListWithMarkets(c.Request.Context(), currentUserID(c), c.Query("currency"))
+	if errors.Is(err, market.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if len(views) > 0 {
+		c.Header("X-Currency", views[0].Currency)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *WatchlistHandler) getWatchlist(c *gin.Context) {
+	view, err := h.service.GetWithMarkets(c.Request.Context(), c.Param("id"), currentUserID(c), c.Query("currency"))
+	if errors.Is(err, watchlist.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, market.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Currency", view.Currency)
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *WatchlistHandler) createWatchlist(c *gin.Context) {
+	var req watchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.service.Create(c.Request.Context(), models.Watchlist{
+		UserID: currentUserID(c),
+		Name:   req.Name,
+		Items:  req.Items,
+	})
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+func (h *WatchlistHandler) updateWatchlist(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": watchlist.ErrNotFound.Error()})
+		return
+	}
+	var req watchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.service.Update(c.Request.Context(), models.Watchlist{
+		ID:     id,
+		UserID: currentUserID(c),
+		Name:   req.Name,
+		Items:  req.Items,
+	})
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *WatchlistHandler) deleteWatchlist(c *gin.Context) {
+	err := h.service.Delete(c.Request.Context(), c.Param("id"), currentUserID(c))
+	if errors.Is(err, watchlist.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WatchlistHandler) addItem(c *gin.Context) {
+	var req watchlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item := models.WatchlistItem{CoinID: req.CoinID}
+	if req.Note != nil {
+		item.Note = *req.Note
+	}
+	list, err := h.service.AddItem(c.Request.Context(), c.Param("id"), currentUserID(c), item, req.Position)
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+// updateItem edits a coin's note and/or moves it to a new position
+func (h *WatchlistHandler) updateItem(c *gin.Context) {
+	var req watchlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.service.UpdateItem(c.Request.Context(), c.Param("id"), currentUserID(c), c.Param("coinId"), req.Note, req.Position)
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *WatchlistHandler) removeItem(c *gin.Context) {
+	list, err := h.service.RemoveItem(c.Request.Context(), c.Param("id"), currentUserID(c), c.Param("coinId"))
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AlertCondition string
+
+const (
+	AlertPriceAbove     AlertCondition = "price_above"
+	AlertPriceBelow     AlertCondition = "price_below"
+	AlertChange24h      AlertCondition = "change_24h" // absolute 24h change of at least Threshold percent
+	AlertPortfolioAbove AlertCondition = "portfolio_above"
+	AlertPortfolioBelow AlertCondition = "portfolio_below"
+)
+
+func (c AlertCondition) Valid() bool {
+	switch c {
+	case AlertPriceAbove, AlertPriceBelow, AlertChange24h, AlertPortfolioAbove, AlertPortfolioBelow:
+		return true
+	}
+	return false
+}
+
+// OnPortfolio reports whether the condition watches a portfolio's value
+// rather than a coin
+func (c AlertCondition) OnPortfolio() bool {
+	return c == AlertPortfolioAbove || c == AlertPortfolioBelow
+}
+
+type AlertMode string
+
+const (
+	// AlertOnce fires the first time the condition holds, then disables the rule
+	AlertOnce AlertMode = "once"
+	// AlertRecurring fires each time the condition starts to hold
+	AlertRecurring AlertMode = "recurring"
+	// AlertCooldown fires whenever the condition holds, at most once per cooldown
+	AlertCooldown AlertMode = "cooldown"
+)
+
+func (m AlertMode) Valid() bool {
+	switch m {
+	case AlertOnce, AlertRecurring, AlertCooldown:
+		return true
+	}
+	return false
+}
+
+const (
+	ChannelInbox   = "inbox"
+	ChannelWebhook = "webhook"
+)
+
+type AlertRule struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"userId"`
+	Name            string             `bson:"name" json:"name"`
+	Condition       AlertCondition     `bson:"condition" json:"condition"`
+	CoinID          string             `bson:"coin_id,omitempty" json:"coinId,omitempty"`
+	PortfolioID     string             `bson:"portfolio_id,omitempty" json:"portfolioId,omitempty"`
+	Threshold       float64            `bson:"threshold" json:"threshold"`
+	Currency        string             `bson:"currency" json:"currency"`
+	Mode            AlertMode          `bson:"mode" json:"mode"`
+	CooldownMinutes int                `bson:"cooldown_minutes,omitempty" json:"cooldownMinutes,omitempty"`
+	Channels        []string           `bson:"channels" json:"channels"`
+	WebhookURL      string             `bson:"webhook_url,omitempty" json:"webhookUrl,omitempty"`
+	Enabled         bool               `bson:"enabled" json:"enabled"`
+	CreatedAt       primitive.DateTime `bson:"created_at" json:"createdAt"`
+	// UpdatedAt changes on every edit, so the engine can tell whether the
+	// rule it evaluated is still current
+	UpdatedAt primitive.DateTime `bson:"updated_at,omitempty" json:"updatedAt"`
+
+	// Evaluation state
+	LastTriggeredAt *primitive.DateTime `bson:"last_triggered_at,omitempty" json:"lastTriggeredAt"`
+	// Matching records whether the condition held at the last evaluation, so
+	// recurring rules fire only when it starts to hold
+	Matching bool `bson:"matching" json:"matching"`
+}
+
+// AlertEvent is the history record of a fired rule
+type AlertEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RuleID      primitive.ObjectID `bson:"rule_id" json:"ruleId"`
+	UserID      string             `bson:"user_id" json:"userId"`
+	Message     string             `bson:"message" json:"message"`
+	Value       float64            `bson:"value" json:"value"`
+	Threshold   float64            `bson:"threshold" json:"threshold"`
+	Currency    string             `bson:"currency" json:"currency"`
+	TriggeredAt primitive.DateTime `bson:"triggered_at" json:"triggeredAt"`
+	// Delivery maps each channel to "ok" or its error
+	Delivery map[string]string `bson:"delivery" json:"delivery"`
+}
+
+// InboxMessage is an in-app notification
+type InboxMessage struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    string              `bson:"user_id" json:"userId"`
+	EventID   primitive.ObjectID  `bson:"event_id,omitempty" json:"eventId"`
+	Title     string              `bson:"title" json:"title"`
+	Body      string              `bson:"body" json:"body"`
+	CreatedAt primitive.DateTime  `bson:"created_at" json:"createdAt"`
+	ReadAt    *primitive.DateTime `bson:"read_at,omitempty" json:"readAt"`
+}
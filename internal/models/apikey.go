@@ -0,0 +1,60 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScopePortfolioRead = "portfolio:read"
+	ScopeHoldingsWrite = "holdings:write"
+	ScopeAdmin         = "admin" // implies every other scope and key management
+)
+
+// APIKey is a personal access key. Only a SHA-256 hash of the key is stored;
+// Prefix keeps the first characters so users can tell keys apart.
+type APIKey struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     string              `bson:"user_id" json:"userId"`
+	Name       string              `bson:"name" json:"name"`
+	Prefix     string              `bson:"prefix" json:"prefix"`
+	Hash       string              `bson:"hash" json:"-"`
+	Scopes     []string            `bson:"scopes" json:"scopes"`
+	CreatedAt  primitive.DateTime  `bson:"created_at" json:"createdAt"`
+	LastUsedAt *primitive.DateTime `bson:"last_used_at,omitempty" json:"lastUsedAt"`
+	ExpiresAt  *primitive.DateTime `bson:"expires_at,omitempty" json:"expiresAt"`
+	RevokedAt  *primitive.DateTime `bson:"revoked_at,omitempty" json:"revokedAt"`
+}
+
+// HasScope reports whether the key grants scope, directly or through admin
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopePortfolioRead, ScopeHoldingsWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID string
+	Scopes []string
+	// APIKeyID is set when the caller authenticated with an API key
+	APIKeyID string
+	// Admin is set for users on the ADMIN_EMAILS allowlist; an API key
+	// additionally needs the admin scope
+	Admin bool
+}
+
+// HasScope reports whether the principal may act with scope
+func (p Principal) HasScope(scope string) bool {
+	return APIKey{Scopes: p.Scopes}.HasScope(scope)
+}
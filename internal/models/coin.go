@@ -0,0 +1,14 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Coin is an entry of the local coin catalog
+type Coin struct {
+	ID        string             `bson:"_id" json:"id"`
+	Symbol    string             `bson:"symbol" json:"symbol"`
+	Name      string             `bson:"name" json:"name"`
+	Platforms map[string]string  `bson:"platforms,omitempty" json:"platforms,omitempty"` // chain -> contract address
+	SyncedAt  primitive.DateTime `bson:"synced_at" json:"syncedAt"`
+}
@@ -7,17 +7,46 @@ import (
 )
 
 type Holding struct {
-	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID string             `bson:"user_id" json:"userId"`
-	CoinID string             `bson:"coin_id" json:"coinId"`
-	Amount float64            `bson:"amount" json:"amount"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"user_id" json:"userId"`
+	PortfolioID string             `bson:"portfolio_id,omitempty" json:"portfolioId"`
+	CoinID      string             `bson:"coin_id" json:"coinId"`
+	Amount      float64            `bson:"amount" json:"amount"`
+	// Version increments on every update; holdings stored before versioning
+	// read as version 0
+	Version int64 `bson:"version" json:"version"`
 }
 
+const (
+	SnapshotSourceScheduled = "scheduled"
+	SnapshotSourceClient    = "client"
+)
+
+// Snapshot records a portfolio's value at a point in time. TotalValue is
+// always computed by the server; snapshots stored before that have no
+// Source or Currency.
 type Snapshot struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID     string             `bson:"user_id" json:"userId"`
-	TotalValue float64            `bson:"total_value" json:"totalValue"`
-	Timestamp  primitive.DateTime `bson:"timestamp" json:"timestamp"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"user_id" json:"userId"`
+	PortfolioID string             `bson:"portfolio_id,omitempty" json:"portfolioId"`
+	TotalValue  float64            `bson:"total_value" json:"totalValue"`
+	Currency    string             `bson:"currency,omitempty" json:"currency,omitempty"`
+	Source      string             `bson:"source,omitempty" json:"source,omitempty"`
+	// PriceSource names the market providers the asset prices came from,
+	// comma-separated when the failover chain answered for different coins
+	PriceSource string             `bson:"price_source,omitempty" json:"priceSource,omitempty"`
+	Assets      []SnapshotAsset    `bson:"assets,omitempty" json:"assets,omitempty"`
+	Timestamp   primitive.DateTime `bson:"timestamp" json:"timestamp"`
+}
+
+// SnapshotAsset is one coin's position within a snapshot, priced in the
+// snapshot's currency. Unpriced coins have zero Price and Value.
+type SnapshotAsset struct {
+	CoinID           string  `bson:"coin_id" json:"coinId"`
+	Amount           float64 `bson:"amount" json:"amount"`
+	Price            float64 `bson:"price" json:"price"`
+	Value            float64 `bson:"value" json:"value"`
+	PriceUnavailable bool    `bson:"price_unavailable,omitempty" json:"priceUnavailable,omitempty"`
 }
 
 func ToPrimitiveDateTime(t time.Time) primitive.DateTime {
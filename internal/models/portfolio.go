@@ -0,0 +1,41 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultPortfolioID names the portfolio every user has implicitly. Records
+// stored before named portfolios existed carry no portfolio ID and belong to it.
+const DefaultPortfolioID = "default"
+
+// Portfolio groups holdings, transactions and snapshots. Empty BaseCurrency
+// and CostBasisMethod fall back to the user's settings.
+type Portfolio struct {
+	ID              string             `bson:"portfolio_id" json:"id"`
+	UserID          string             `bson:"user_id" json:"userId"`
+	Name            string             `bson:"name" json:"name"`
+	BaseCurrency    string             `bson:"base_currency,omitempty" json:"baseCurrency,omitempty"`
+	CostBasisMethod string             `bson:"cost_basis_method,omitempty" json:"costBasisMethod,omitempty"`
+	CreatedAt       primitive.DateTime `bson:"created_at" json:"createdAt"`
+
+	Targets []TargetWeight `bson:"targets,omitempty" json:"targets,omitempty"`
+	// DriftTolerancePercent is how many percentage points a coin's weight may
+	// drift from its target before rebalancing is suggested
+	DriftTolerancePercent float64 `bson:"drift_tolerance_percent,omitempty" json:"driftTolerancePercent,omitempty"`
+}
+
+// TargetWeight is a coin's desired share of a portfolio in percent.
+// TolerancePercent overrides the portfolio's band for this coin when set.
+type TargetWeight struct {
+	CoinID           string  `bson:"coin_id" json:"coinId"`
+	Weight           float64 `bson:"weight" json:"weight"`
+	TolerancePercent float64 `bson:"tolerance_percent,omitempty" json:"tolerancePercent,omitempty"`
+}
+
+// PortfolioOf maps a record's stored portfolio ID to the portfolio it belongs to
+func PortfolioOf(portfolioID string) string {
+	if portfolioID == "" {
+		return DefaultPortfolioID
+	}
+	return portfolioID
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceRecord is a stored USD price observation for a coin
+type PriceRecord struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CoinID    string             `bson:"coin_id" json:"coinId"`
+	Timestamp primitive.DateTime `bson:"timestamp" json:"timestamp"`
+	Price     float64            `bson:"price" json:"price"`
+	Source    string             `bson:"source" json:"source"`
+}
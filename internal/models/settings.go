@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Lot-matching methods for realized gains
+const (
+	CostBasisFIFO    = "fifo"
+	CostBasisLIFO    = "lifo"
+	CostBasisHIFO    = "hifo"
+	CostBasisAverage = "average"
+)
+
+const (
+	SnapshotHourly = "hourly"
+	SnapshotDaily  = "daily"
+	SnapshotOff    = "off"
+)
+
+// PortfolioSettings holds per-user portfolio preferences
+type PortfolioSettings struct {
+	UserID          string `bson:"user_id" json:"userId"`
+	CostBasisMethod string `bson:"cost_basis_method" json:"costBasisMethod"`
+	Currency        string `bson:"currency" json:"currency"` // default valuation currency
+	// SnapshotFrequency controls scheduled snapshots of every portfolio the
+	// user owns
+	SnapshotFrequency string `bson:"snapshot_frequency,omitempty" json:"snapshotFrequency"`
+}
+
+// ValidCostBasisMethod reports whether method is one of the lot-matching methods
+func ValidCostBasisMethod(method string) bool {
+	switch method {
+	case CostBasisFIFO, CostBasisLIFO, CostBasisHIFO, CostBasisAverage:
+		return true
+	}
+	return false
+}
+
+// SnapshotInterval maps a snapshot frequency to its period; ok is false for
+// "off" and unknown values
+func SnapshotInterval(frequency string) (interval time.Duration, ok bool) {
+	switch frequency {
+	case SnapshotHourly:
+		return time.Hour, true
+	case SnapshotDaily:
+		return 24 * time.Hour, true
+	}
+	return 0, false
+}
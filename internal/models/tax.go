@@ -0,0 +1,44 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// TaxJurisdiction sets the tax year and the holding period after which a
+// gain is long-term: more than LongTermYears calendar years, or more than
+// LongTermDays days when that override is set. With neither, gains are not
+// split by term.
+type TaxJurisdiction struct {
+	Code           string     `json:"code"`
+	Name           string     `json:"name"`
+	LongTermYears  int        `json:"longTermYears,omitempty"`
+	LongTermDays   int        `json:"longTermDays,omitempty"`
+	YearStartMonth time.Month `json:"yearStartMonth"`
+	YearStartDay   int        `json:"yearStartDay"`
+}
+
+var taxJurisdictions = map[string]TaxJurisdiction{
+	"us": {Code: "us", Name: "United States", LongTermYears: 1, YearStartMonth: time.January, YearStartDay: 1},
+	"uk": {Code: "uk", Name: "United Kingdom", YearStartMonth: time.April, YearStartDay: 6},
+	"de": {Code: "de", Name: "Germany", LongTermYears: 1, YearStartMonth: time.January, YearStartDay: 1},
+	"au": {Code: "au", Name: "Australia", LongTermYears: 1, YearStartMonth: time.July, YearStartDay: 1},
+	"ca": {Code: "ca", Name: "Canada", YearStartMonth: time.January, YearStartDay: 1},
+	"in": {Code: "in", Name: "India", YearStartMonth: time.April, YearStartDay: 1},
+}
+
+// LookupTaxJurisdiction returns the jurisdiction for a lower-case code
+func LookupTaxJurisdiction(code string) (TaxJurisdiction, bool) {
+	j, ok := taxJurisdictions[code]
+	return j, ok
+}
+
+// TaxJurisdictionCodes lists the known jurisdiction codes
+func TaxJurisdictionCodes() []string {
+	codes := make([]string, 0, len(taxJurisdictions))
+	for code := range taxJurisdictions {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TransactionType string
+
+const (
+	TransactionBuy         TransactionType = "buy"
+	TransactionSell        TransactionType = "sell"
+	TransactionTransferIn  TransactionType = "transfer_in"
+	TransactionTransferOut TransactionType = "transfer_out"
+	TransactionFee         TransactionType = "fee"
+	// TransactionIncome is coin received as earnings, such as staking
+	// rewards or airdrops, valued at UnitPrice when received
+	TransactionIncome TransactionType = "income"
+)
+
+// Income categories for TransactionIncome entries
+const (
+	IncomeStaking  = "staking"
+	IncomeAirdrop  = "airdrop"
+	IncomeMining   = "mining"
+	IncomeInterest = "interest"
+	IncomeReward   = "reward"
+)
+
+// ValidIncomeType reports whether t is one of the income categories
+func ValidIncomeType(t string) bool {
+	switch t {
+	case IncomeStaking, IncomeAirdrop, IncomeMining, IncomeInterest, IncomeReward:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether t is one of the known ledger entry types
+func (t TransactionType) Valid() bool {
+	switch t {
+	case TransactionBuy, TransactionSell, TransactionTransferIn, TransactionTransferOut, TransactionFee, TransactionIncome:
+		return true
+	}
+	return false
+}
+
+// Transaction is a single ledger entry. Holdings are derived by replaying these.
+type Transaction struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        string             `bson:"user_id" json:"userId"`
+	PortfolioID   string             `bson:"portfolio_id,omitempty" json:"portfolioId"`
+	CoinID        string             `bson:"coin_id" json:"coinId"`
+	Type          TransactionType    `bson:"type" json:"type"`
+	Quantity      float64            `bson:"quantity" json:"quantity"`
+	UnitPrice     float64            `bson:"unit_price" json:"unitPrice"`
+	QuoteCurrency string             `bson:"quote_currency" json:"quoteCurrency"`
+	Timestamp     primitive.DateTime `bson:"timestamp" json:"timestamp"`
+	Notes         string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	// IncomeType categorises income entries and is empty for every other type
+	IncomeType string `bson:"income_type,omitempty" json:"incomeType,omitempty"`
+	// ExternalID is the exchange's own ID for imported entries
+	ExternalID string `bson:"external_id,omitempty" json:"externalId,omitempty"`
+}
+
+// QuantityDelta returns the signed change this entry applies to the coin balance
+func (t Transaction) QuantityDelta() float64 {
+	switch t.Type {
+	case TransactionBuy, TransactionTransferIn, TransactionIncome:
+		return t.Quantity
+	case TransactionSell, TransactionTransferOut, TransactionFee:
+		return -t.Quantity
+	}
+	return 0
+}
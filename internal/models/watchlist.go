@@ -0,0 +1,21 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Watchlist is a named, ordered list of coins a user follows without owning
+type Watchlist struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string             `bson:"user_id" json:"userId"`
+	Name      string             `bson:"name" json:"name"`
+	Items     []WatchlistItem    `bson:"items" json:"items"` // display order
+	CreatedAt primitive.DateTime `bson:"created_at" json:"createdAt"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updatedAt"`
+}
+
+type WatchlistItem struct {
+	CoinID  string             `bson:"coin_id" json:"coinId"`
+	Note    string             `bson:"note,omitempty" json:"note,omitempty"`
+	AddedAt primitive.DateTime `bson:"added_at" json:"addedAt"`
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type AlertRepository interface {
+	CreateAlertRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error)
+	ListAlertRules(ctx context.Context, userID string) ([]models.AlertRule, error)
+	// ListEnabledAlertRules returns every user's enabled rules for evaluation
+	ListEnabledAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	GetAlertRule(ctx context.Context, id string, userID string) (*models.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, rule models.AlertRule) error
+	// UpdateAlertState stores only the evaluation state of a rule, disabling
+	// it when rule.Enabled was cleared. It returns ErrVersionConflict when the
+	// rule was edited, disabled or deleted after rule was read.
+	UpdateAlertState(ctx context.Context, rule models.AlertRule) error
+	DeleteAlertRule(ctx context.Context, id string, userID string) error
+
+	CreateAlertEvent(ctx context.Context, event models.AlertEvent) (*models.AlertEvent, error)
+	// SetAlertDelivery records the outcome of delivering an event on channel
+	SetAlertDelivery(ctx context.Context, eventID primitive.ObjectID, channel string, status string) error
+	// ListAlertEvents returns the newest events first; an empty ruleID lists all rules
+	ListAlertEvents(ctx context.Context, userID string, ruleID string, limit int) ([]models.AlertEvent, error)
+
+	CreateInboxMessage(ctx context.Context, msg models.InboxMessage) (*models.InboxMessage, error)
+	ListInboxMessages(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.InboxMessage, error)
+	MarkInboxMessageRead(ctx context.Context, id string, userID string, at time.Time) error
+}
+
+type MongoAlertRepository struct {
+	rules  *mongo.Collection
+	events *mongo.Collection
+	inbox  *mongo.Collection
+}
+
+func NewMongoAlertRepository(db *mongo.Database) *MongoAlertRepository {
+	return &MongoAlertRepository{
+		rules:  db.Collection("alert_rules"),
+		events: db.Collection("alert_events"),
+		inbox:  db.Collection("inbox"),
+	}
+}
+
+func (r *MongoAlertRepository) CreateAlertRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.rules.InsertOne(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = res.InsertedID.(primitive.ObjectID)
+	return &rule, nil
+}
+
+func (r *MongoAlertRepository) ListAlertRules(ctx context.Context, userID string) ([]models.AlertRule, error) {
+	return r.findRules(ctx, bson.M{"user_id": userID})
+}
+
+func (r *MongoAlertRepository) ListEnabledAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	return r.findRules(ctx, bson.M{"enabled": true})
+}
+
+func (r *MongoAlertRepository) findRules(ctx context.Context, filter bson.M) ([]models.AlertRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.rules.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var rules []models.AlertRule
+	if err := cur.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *MongoAlertRepository) GetAlertRule(ctx context.Context, id string, userID string) (*models.AlertRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var rule models.AlertRule
+	err = r.rules.FindOne(ctx, bson.M{"_id": objID, "user_id": userID}).Decode(&rule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *MongoAlertRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.rules.ReplaceOne(ctx, bson.M{"_id": rule.ID, "user_id": rule.UserID}, rule)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoAlertRepository) UpdateAlertState(ctx context.Context, rule models.AlertRule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"matching": rule.Matching}
+	if !rule.Enabled {
+		set["enabled"] = false
+	}
+	if rule.LastTriggeredAt != nil {
+		set["last_triggered_at"] = *rule.LastTriggeredAt
+	}
+	// Rules stored before updated_at existed have no such field
+	var updatedAt interface{}
+	if rule.UpdatedAt != 0 {
+		updatedAt = rule.UpdatedAt
+	}
+	filter := bson.M{"_id": rule.ID, "enabled": true, "updated_at": updatedAt}
+	res, err := r.rules.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *MongoAlertRepository) DeleteAlertRule(ctx context.Context, id string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res, err := r.rules.DeleteOne(ctx, bson.M{"_id": objID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoAlertRepository) CreateAlertEvent(ctx context.Context, event models.AlertEvent) (*models.AlertEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.events.InsertOne(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event.ID = res.InsertedID.(primitive.ObjectID)
+	return &event, nil
+}
+
+func (r *MongoAlertRepository) SetAlertDelivery(ctx context.Context, eventID primitive.ObjectID, channel string, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.events.UpdateOne(ctx, bson.M{"_id": eventID}, bson.M{"$set": bson.M{"delivery." + channel: status}})
+	return err
+}
+
+func (r *MongoAlertRepository) ListAlertEvents(ctx context.Context, userID string, ruleID string, limit int) ([]models.AlertEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID}
+	if ruleID != "" {
+		objID, err := primitive.ObjectIDFromHex(ruleID)
+		if err != nil {
+			return nil, nil
+		}
+		filter["rule_id"] = objID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "triggered_at", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := r.events.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var events []models.AlertEvent
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *MongoAlertRepository) CreateInboxMessage(ctx context.Context, msg models.InboxMessage) (*models.InboxMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.inbox.InsertOne(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = res.InsertedID.(primitive.ObjectID)
+	return &msg, nil
+}
+
+func (r *MongoAlertRepository) ListInboxMessages(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.InboxMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read_at"] = nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := r.inbox.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var msgs []models.InboxMessage
+	if err := cur.All(ctx, &msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (r *MongoAlertRepository) MarkInboxMessageRead(ctx context.Context, id string, userID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res, err := r.inbox.UpdateOne(ctx,
+		bson.M{"_id": objID, "user_id": userID},
+		bson.M{"$set": bson.M{"read_at": models.ToPrimitiveDateTime(at)}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type MemoryAlertRepository struct {
+	rules  map[string]models.AlertRule // key: rule ID
+	events []models.AlertEvent
+	inbox  []models.InboxMessage
+	mu     sync.RWMutex
+}
+
+func NewMemoryAlertRepository() *MemoryAlertRepository {
+	return &MemoryAlertRepository{
+		rules: make(map[string]models.AlertRule),
+	}
+}
+
+func (r *MemoryAlertRepository) CreateAlertRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rule.ID.IsZero() {
+		rule.ID = primitive.NewObjectID()
+	}
+	r.rules[rule.ID.Hex()] = rule
+	return &rule, nil
+}
+
+func (r *MemoryAlertRepository) ListAlertRules(ctx context.Context, userID string) ([]models.AlertRule, error) {
+	return r.filterRules(func(rule models.AlertRule) bool { return rule.UserID == userID }), nil
+}
+
+func (r *MemoryAlertRepository) ListEnabledAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	return r.filterRules(func(rule models.AlertRule) bool { return rule.Enabled }), nil
+}
+
+func (r *MemoryAlertRepository) filterRules(keep func(models.AlertRule) bool) []models.AlertRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.AlertRule
+	for _, rule := range r.rules {
+		if keep(rule) {
+			result = append(result, rule)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt < result[j].CreatedAt
+		}
+		return bytes.Compare(result[i].ID[:], result[j].ID[:]) < 0
+	})
+	return result
+}
+
+func (r *MemoryAlertRepository) GetAlertRule(ctx context.Context, id string, userID string) (*models.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, exists := r.rules[id]
+	if !exists || rule.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &rule, nil
+}
+
+func (r *MemoryAlertRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.rules[rule.ID.Hex()]
+	if !exists || existing.UserID != rule.UserID {
+		return ErrNotFound
+	}
+	r.rules[rule.ID.Hex()] = rule
+	return nil
+}
+
+func (r *MemoryAlertRepository) UpdateAlertState(ctx context.Context, rule models.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.rules[rule.ID.Hex()]
+	if !exists || !existing.Enabled || existing.UpdatedAt != rule.UpdatedAt {
+		return ErrVersionConflict
+	}
+	existing.Matching = rule.Matching
+	if !rule.Enabled {
+		existing.Enabled = false
+	}
+	if rule.LastTriggeredAt != nil {
+		existing.LastTriggeredAt = rule.LastTriggeredAt
+	}
+	r.rules[rule.ID.Hex()] = existing
+	return nil
+}
+
+func (r *MemoryAlertRepository) DeleteAlertRule(ctx context.Context, id string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule, exists := r.rules[id]
+	if !exists || rule.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.rules, id)
+	return nil
+}
+
+func (r *MemoryAlertRepository) CreateAlertEvent(ctx context.Context, event models.AlertEvent) (*models.AlertEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	r.events = append(r.events, event)
+	return &event, nil
+}
+
+func (r *MemoryAlertRepository) SetAlertDelivery(ctx context.Context, eventID primitive.ObjectID, channel string, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.events {
+		if r.events[i].ID == eventID {
+			delivery := make(map[string]string, len(r.events[i].Delivery))
+			for ch, s := range r.events[i].Delivery {
+				delivery[ch] = s
+			}
+			delivery[channel] = status
+			r.events[i].Delivery = delivery
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ListAlertEvents walks the append-only log backwards, so results are newest first
+func (r *MemoryAlertRepository) ListAlertEvents(ctx context.Context, userID string, ruleID string, limit int) ([]models.AlertEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.AlertEvent
+	for i := len(r.events) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		event := r.events[i]
+		if event.UserID != userID || (ruleID != "" && event.RuleID.Hex() != ruleID) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+func (r *MemoryAlertRepository) CreateInboxMessage(ctx context.Context, msg models.InboxMessage) (*models.InboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if msg.ID.IsZero() {
+		msg.ID = primitive.NewObjectID()
+	}
+	r.inbox = append(r.inbox, msg)
+	return &msg, nil
+}
+
+func (r *MemoryAlertRepository) ListInboxMessages(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.InboxMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.InboxMessage
+	for i := len(r.inbox) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		msg := r.inbox[i]
+		if msg.UserID != userID || (unreadOnly && msg.ReadAt != nil) {
+			continue
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
+
+func (r *MemoryAlertRepository) MarkInboxMessageRead(ctx context.Context, id string, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, msg := range r.inbox {
+		if msg.ID.Hex() == id && msg.UserID == userID {
+			readAt := models.ToPrimitiveDateTime(at)
+			r.inbox[i].ReadAt = &readAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type MemoryAPIKeyRepository struct {
+	keys map[string]models.APIKey // key: API key ID
+	mu   sync.RWMutex
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys: make(map[string]models.APIKey),
+	}
+}
+
+func (r *MemoryAPIKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	r.keys[key.ID.Hex()] = key
+	return &key, nil
+}
+
+func (r *MemoryAPIKeyRepository) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt < result[j].CreatedAt
+	})
+	return result, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAPIKeyRepository) RevokeAPIKey(ctx context.Context, id string, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id]
+	if !exists || key.UserID != userID {
+		return ErrNotFound
+	}
+	revokedAt := models.ToPrimitiveDateTime(at)
+	key.RevokedAt = &revokedAt
+	r.keys[id] = key
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id.Hex()]
+	if !exists {
+		return ErrNotFound
+	}
+	lastUsed := models.ToPrimitiveDateTime(at)
+	key.LastUsedAt = &lastUsed
+	r.keys[id.Hex()] = key
+	return nil
+}
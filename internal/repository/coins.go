@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// coinWriteBatch caps the number of upserts sent in one bulk write
+const coinWriteBatch = 1000
+
+type CoinRepository interface {
+	// ReplaceCoins stores coins as the whole catalog, dropping coins from
+	// earlier syncs that are no longer listed
+	ReplaceCoins(ctx context.Context, coins []models.Coin, syncedAt time.Time) error
+	ListCoins(ctx context.Context) ([]models.Coin, error)
+}
+
+type MongoCoinRepository struct {
+	coins *mongo.Collection
+}
+
+func NewMongoCoinRepository(db *mongo.Database) *MongoCoinRepository {
+	return &MongoCoinRepository{
+		coins: db.Collection("coins"),
+	}
+}
+
+func (r *MongoCoinRepository) ReplaceCoins(ctx context.Context, coins []models.Coin, syncedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	stamp := models.ToPrimitiveDateTime(syncedAt)
+	for start := 0; start < len(coins); start += coinWriteBatch {
+		end := start + coinWriteBatch
+		if end > len(coins) {
+			end = len(coins)
+		}
+		writes := make([]mongo.WriteModel, 0, end-start)
+		for _, coin := range coins[start:end] {
+			coin.SyncedAt = stamp
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": coin.ID}).
+				SetReplacement(coin).
+				SetUpsert(true))
+		}
+		if _, err := r.coins.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	_, err := r.coins.DeleteMany(ctx, bson.M{"synced_at": bson.M{"$lt": stamp}})
+	return err
+}
+
+func (r *MongoCoinRepository) ListCoins(ctx context.Context) ([]models.Coin, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cur, err := r.coins.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var coins []models.Coin
+	if err := cur.All(ctx, &coins); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
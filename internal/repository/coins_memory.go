@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type MemoryCoinRepository struct {
+	coins []models.Coin
+	mu    sync.RWMutex
+}
+
+func NewMemoryCoinRepository() *MemoryCoinRepository {
+	return &MemoryCoinRepository{}
+}
+
+func (r *MemoryCoinRepository) ReplaceCoins(ctx context.Context, coins []models.Coin, syncedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp := models.ToPrimitiveDateTime(syncedAt)
+	r.coins = make([]models.Coin, len(coins))
+	for i, coin := range coins {
+		coin.SyncedAt = stamp
+		r.coins[i] = coin
+	}
+	return nil
+}
+
+func (r *MemoryCoinRepository) ListCoins(ctx context.Context) ([]models.Coin, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.Coin(nil), r.coins...), nil
+}
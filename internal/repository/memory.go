@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +16,10 @@ import (
 type MemoryPortfolioRepository struct {
 	holdings map[string]models.Holding // key: holding ID
 	snapshots map[string]models.Snapshot // key: snapshot ID
+	transactions map[string]models.Transaction // key: transaction ID
+	settings map[string]models.PortfolioSettings // key: user ID
+	portfolios map[string]models.Portfolio // key: user ID + "/" + portfolio ID
+	ledgerLocks map[string]chan struct{} // key: ledgerLockKey
 	mu       sync.RWMutex
 }
 
@@ -21,22 +27,43 @@ func NewMemoryPortfolioRepository() *MemoryPortfolioRepository {
 	return &MemoryPortfolioRepository{
 		holdings:  make(map[string]models.Holding),
 		snapshots: make(map[string]models.Snapshot),
+		transactions: make(map[string]models.Transaction),
+		settings: make(map[string]models.PortfolioSettings),
+		portfolios: make(map[string]models.Portfolio),
+		ledgerLocks: make(map[string]chan struct{}),
 	}
 }
 
-func (r *MemoryPortfolioRepository) ListHoldings(ctx context.Context, userID string) ([]models.Holding, error) {
+// inPortfolio reports whether a record stored under recordPortfolioID
+// matches the portfolioID filter; an empty filter matches everything
+func inPortfolio(recordPortfolioID, portfolioID string) bool {
+	return portfolioID == "" || models.PortfolioOf(recordPortfolioID) == portfolioID
+}
+
+func (r *MemoryPortfolioRepository) ListHoldings(ctx context.Context, userID string, portfolioID string) ([]models.Holding, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var result []models.Holding
 	for _, holding := range r.holdings {
-		if holding.UserID == userID {
+		if holding.UserID == userID && inPortfolio(holding.PortfolioID, portfolioID) {
 			result = append(result, holding)
 		}
 	}
 	return result, nil
 }
 
+func (r *MemoryPortfolioRepository) GetHolding(ctx context.Context, id string, userID string, portfolioID string) (*models.Holding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	holding, exists := r.holdings[id]
+	if !exists || holding.UserID != userID || !inPortfolio(holding.PortfolioID, portfolioID) {
+		return nil, ErrNotFound
+	}
+	return &holding, nil
+}
+
 func (r *MemoryPortfolioRepository) CreateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -51,7 +78,23 @@ func (r *MemoryPortfolioRepository) CreateHolding(ctx context.Context, holding m
 	return &holding, nil
 }
 
-func (r *MemoryPortfolioRepository) DeleteHolding(ctx context.Context, id string, userID string) error {
+func (r *MemoryPortfolioRepository) UpdateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.holdings[holding.ID.Hex()]
+	if !exists || existing.UserID != holding.UserID || !inPortfolio(existing.PortfolioID, holding.PortfolioID) {
+		return nil, ErrNotFound
+	}
+	if existing.Version != holding.Version {
+		return nil, ErrVersionConflict
+	}
+	holding.Version++
+	r.holdings[holding.ID.Hex()] = holding
+	return &holding, nil
+}
+
+func (r *MemoryPortfolioRepository) DeleteHolding(ctx context.Context, id string, userID string, portfolioID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -60,7 +103,7 @@ func (r *MemoryPortfolioRepository) DeleteHolding(ctx context.Context, id string
 		return nil // Already deleted or doesn't exist
 	}
 
-	if holding.UserID != userID {
+	if holding.UserID != userID || !inPortfolio(holding.PortfolioID, portfolioID) {
 		return nil // Not the user's holding, but don't error
 	}
 
@@ -68,19 +111,66 @@ func (r *MemoryPortfolioRepository) DeleteHolding(ctx context.Context, id string
 	return nil
 }
 
-func (r *MemoryPortfolioRepository) ListSnapshots(ctx context.Context, userID string) ([]models.Snapshot, error) {
+func (r *MemoryPortfolioRepository) ListSnapshots(ctx context.Context, userID string, portfolioID string, query SnapshotQuery) ([]models.Snapshot, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	from := models.ToPrimitiveDateTime(query.From)
+	to := models.ToPrimitiveDateTime(query.To)
 	var result []models.Snapshot
 	for _, snapshot := range r.snapshots {
-		if snapshot.UserID == userID {
-			result = append(result, snapshot)
+		if snapshot.UserID != userID || !inPortfolio(snapshot.PortfolioID, portfolioID) {
+			continue
+		}
+		if (!query.From.IsZero() && snapshot.Timestamp < from) || (!query.To.IsZero() && snapshot.Timestamp >= to) {
+			continue
+		}
+		if query.After != nil && !snapshotAfter(snapshot, *query.After, query.Descending) {
+			continue
 		}
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return snapshotAfter(result[j], SnapshotCursor{Timestamp: result[i].Timestamp, ID: result[i].ID}, query.Descending)
+	})
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
 	}
 	return result, nil
 }
 
+// snapshotAfter reports whether snapshot sorts strictly after cursor
+func snapshotAfter(snapshot models.Snapshot, cursor SnapshotCursor, descending bool) bool {
+	if snapshot.Timestamp != cursor.Timestamp {
+		return (snapshot.Timestamp > cursor.Timestamp) != descending
+	}
+	cmp := bytes.Compare(snapshot.ID[:], cursor.ID[:])
+	if descending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func (r *MemoryPortfolioRepository) LatestSnapshot(ctx context.Context, userID string, portfolioID string) (*models.Snapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.Snapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.UserID != userID || !inPortfolio(snapshot.PortfolioID, portfolioID) {
+			continue
+		}
+		if latest == nil || snapshot.Timestamp > latest.Timestamp {
+			s := snapshot
+			latest = &s
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
 func (r *MemoryPortfolioRepository) CreateSnapshot(ctx context.Context, snapshot models.Snapshot) (*models.Snapshot, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -100,3 +190,240 @@ func (r *MemoryPortfolioRepository) CreateSnapshot(ctx context.Context, snapshot
 	return &snapshot, nil
 }
 
+func (r *MemoryPortfolioRepository) DeleteSnapshots(ctx context.Context, userID string, portfolioID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, snapshot := range r.snapshots {
+		if snapshot.UserID == userID && snapshot.PortfolioID == portfolioID {
+			delete(r.snapshots, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPortfolioRepository) ListPortfolioOwners(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for _, holding := range r.holdings {
+		seen[holding.UserID] = struct{}{}
+	}
+	for _, tx := range r.transactions {
+		seen[tx.UserID] = struct{}{}
+	}
+	for _, portfolio := range r.portfolios {
+		seen[portfolio.UserID] = struct{}{}
+	}
+	owners := make([]string, 0, len(seen))
+	for userID := range seen {
+		owners = append(owners, userID)
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+func (r *MemoryPortfolioRepository) ListHeldCoins(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for _, holding := range r.holdings {
+		seen[holding.CoinID] = struct{}{}
+	}
+	for _, tx := range r.transactions {
+		seen[tx.CoinID] = struct{}{}
+	}
+	coins := make([]string, 0, len(seen))
+	for coinID := range seen {
+		coins = append(coins, coinID)
+	}
+	sort.Strings(coins)
+	return coins, nil
+}
+
+func (r *MemoryPortfolioRepository) ListTransactions(ctx context.Context, userID string, portfolioID string) ([]models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.Transaction
+	for _, tx := range r.transactions {
+		if tx.UserID == userID && inPortfolio(tx.PortfolioID, portfolioID) {
+			result = append(result, tx)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Timestamp != result[j].Timestamp {
+			return result[i].Timestamp < result[j].Timestamp
+		}
+		return result[i].ID.Hex() < result[j].ID.Hex()
+	})
+	return result, nil
+}
+
+func (r *MemoryPortfolioRepository) EachTransaction(ctx context.Context, userID string, portfolioID string, from, to time.Time, fn func(models.Transaction) error) error {
+	transactions, err := r.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	lower := models.ToPrimitiveDateTime(from)
+	upper := models.ToPrimitiveDateTime(to)
+	for _, tx := range transactions {
+		if (!from.IsZero() && tx.Timestamp < lower) || (!to.IsZero() && tx.Timestamp >= upper) {
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPortfolioRepository) GetTransaction(ctx context.Context, id string, userID string) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, exists := r.transactions[id]
+	if !exists || tx.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &tx, nil
+}
+
+func (r *MemoryPortfolioRepository) CreateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Generate ID if not set
+	if tx.ID.IsZero() {
+		tx.ID = primitive.NewObjectID()
+	}
+
+	r.transactions[tx.ID.Hex()] = tx
+	return &tx, nil
+}
+
+func (r *MemoryPortfolioRepository) UpdateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.transactions[tx.ID.Hex()]
+	if !exists || existing.UserID != tx.UserID {
+		return nil, ErrNotFound
+	}
+
+	r.transactions[tx.ID.Hex()] = tx
+	return &tx, nil
+}
+
+func (r *MemoryPortfolioRepository) DeleteTransaction(ctx context.Context, id string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, exists := r.transactions[id]
+	if !exists || tx.UserID != userID {
+		return ErrNotFound
+	}
+
+	delete(r.transactions, id)
+	return nil
+}
+
+// LockLedger holds a one-slot channel per portfolio so waiting can give up
+// when ctx ends
+func (r *MemoryPortfolioRepository) LockLedger(ctx context.Context, userID string, portfolioID string) (func(), error) {
+	key := ledgerLockKey(userID, portfolioID)
+	r.mu.Lock()
+	lock, ok := r.ledgerLocks[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		r.ledgerLocks[key] = lock
+	}
+	r.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *MemoryPortfolioRepository) ImportRecords(ctx context.Context, holdings []models.Holding, transactions []models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, holding := range holdings {
+		r.holdings[holding.ID.Hex()] = holding
+	}
+	for _, tx := range transactions {
+		r.transactions[tx.ID.Hex()] = tx
+	}
+	return nil
+}
+
+func (r *MemoryPortfolioRepository) GetSettings(ctx context.Context, userID string) (*models.PortfolioSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings, exists := r.settings[userID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &settings, nil
+}
+
+func (r *MemoryPortfolioRepository) SaveSettings(ctx context.Context, settings models.PortfolioSettings) (*models.PortfolioSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings[settings.UserID] = settings
+	return &settings, nil
+}
+
+func (r *MemoryPortfolioRepository) ListPortfolios(ctx context.Context, userID string) ([]models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.Portfolio
+	for _, portfolio := range r.portfolios {
+		if portfolio.UserID == userID {
+			result = append(result, portfolio)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt < result[j].CreatedAt
+	})
+	return result, nil
+}
+
+func (r *MemoryPortfolioRepository) GetPortfolio(ctx context.Context, userID string, id string) (*models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	portfolio, exists := r.portfolios[userID+"/"+id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &portfolio, nil
+}
+
+func (r *MemoryPortfolioRepository) SavePortfolio(ctx context.Context, portfolio models.Portfolio) (*models.Portfolio, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.portfolios[portfolio.UserID+"/"+portfolio.ID] = portfolio
+	return &portfolio, nil
+}
+
+func (r *MemoryPortfolioRepository) DeletePortfolio(ctx context.Context, userID string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.portfolios[userID+"/"+id]; !exists {
+		return ErrNotFound
+	}
+	delete(r.portfolios, userID+"/"+id)
+	return nil
+}
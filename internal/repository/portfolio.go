@@ -2,40 +2,142 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/faisal/crypto/backend/internal/models"
 )
 
+// ErrNotFound is returned when a record does not exist or belongs to another user
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionConflict is returned when an update was based on a stale version
+var ErrVersionConflict = errors.New("version conflict")
+
+// SnapshotQuery selects a page of snapshots ordered by timestamp, then ID.
+// Zero From/To leave that end open and To is exclusive. A zero Limit returns
+// every match.
+type SnapshotQuery struct {
+	From       time.Time
+	To         time.Time
+	After      *SnapshotCursor
+	Limit      int
+	Descending bool
+}
+
+// SnapshotCursor is a keyset position: results resume strictly after it in
+// the query's order. A zero ID makes the cursor a plain time boundary.
+type SnapshotCursor struct {
+	Timestamp primitive.DateTime
+	ID        primitive.ObjectID
+}
+
+// PortfolioRepository list methods take a portfolioID; an empty one matches
+// every portfolio of the user.
 type PortfolioRepository interface {
-	ListHoldings(ctx context.Context, userID string) ([]models.Holding, error)
+	ListHoldings(ctx context.Context, userID string, portfolioID string) ([]models.Holding, error)
+	GetHolding(ctx context.Context, id string, userID string, portfolioID string) (*models.Holding, error)
 	CreateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error)
-	DeleteHolding(ctx context.Context, id string, userID string) error
-	ListSnapshots(ctx context.Context, userID string) ([]models.Snapshot, error)
+	// UpdateHolding replaces the holding only if its stored version is still
+	// holding.Version, and stores it with the version incremented
+	UpdateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error)
+	DeleteHolding(ctx context.Context, id string, userID string, portfolioID string) error
+	ListSnapshots(ctx context.Context, userID string, portfolioID string, query SnapshotQuery) ([]models.Snapshot, error)
+	// LatestSnapshot returns the most recent snapshot or ErrNotFound
+	LatestSnapshot(ctx context.Context, userID string, portfolioID string) (*models.Snapshot, error)
 	CreateSnapshot(ctx context.Context, snapshot models.Snapshot) (*models.Snapshot, error)
+	// DeleteSnapshots removes the history of a named portfolio
+	DeleteSnapshots(ctx context.Context, userID string, portfolioID string) error
+	// ListPortfolioOwners returns every user with holdings, transactions or
+	// named portfolios
+	ListPortfolioOwners(ctx context.Context) ([]string, error)
+	// ListHeldCoins returns every coin ID appearing in any user's holdings
+	// or transactions
+	ListHeldCoins(ctx context.Context) ([]string, error)
+
+	// ListTransactions returns the ledger ordered by timestamp ascending
+	ListTransactions(ctx context.Context, userID string, portfolioID string) ([]models.Transaction, error)
+	// EachTransaction streams the ledger entries in [from, to) to fn in
+	// timestamp order, stopping at the first error fn returns. Zero bounds
+	// leave that end open.
+	EachTransaction(ctx context.Context, userID string, portfolioID string, from, to time.Time, fn func(models.Transaction) error) error
+	GetTransaction(ctx context.Context, id string, userID string) (*models.Transaction, error)
+	CreateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error)
+	UpdateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error)
+	DeleteTransaction(ctx context.Context, id string, userID string) error
+	// LockLedger serializes ledger writes to one portfolio, so a balance
+	// check and the write it guards see no other writer in between. It waits
+	// while the lock is held elsewhere and fails once ctx ends; the returned
+	// function releases it.
+	LockLedger(ctx context.Context, userID string, portfolioID string) (unlock func(), err error)
+	// ImportRecords stores a batch of holdings and transactions together.
+	// Records must already carry their IDs; if any write fails none are kept.
+	ImportRecords(ctx context.Context, holdings []models.Holding, transactions []models.Transaction) error
+
+	GetSettings(ctx context.Context, userID string) (*models.PortfolioSettings, error)
+	SaveSettings(ctx context.Context, settings models.PortfolioSettings) (*models.PortfolioSettings, error)
+
+	ListPortfolios(ctx context.Context, userID string) ([]models.Portfolio, error)
+	GetPortfolio(ctx context.Context, userID string, id string) (*models.Portfolio, error)
+	SavePortfolio(ctx context.Context, portfolio models.Portfolio) (*models.Portfolio, error)
+	DeletePortfolio(ctx context.Context, userID string, id string) error
 }
 
 type MongoPortfolioRepository struct {
-	holdings *mongo.Collection
-	history  *mongo.Collection
+	holdings     *mongo.Collection
+	history      *mongo.Collection
+	transactions *mongo.Collection
+	settings     *mongo.Collection
+	portfolios   *mongo.Collection
+	ledgerLocks  *mongo.Collection
 }
 
 func NewMongoPortfolioRepository(db *mongo.Database) *MongoPortfolioRepository {
 	return &MongoPortfolioRepository{
-		holdings: db.Collection("holdings"),
-		history:  db.Collection("snapshots"),
+		holdings:     db.Collection("holdings"),
+		history:      db.Collection("snapshots"),
+		transactions: db.Collection("transactions"),
+		settings:     db.Collection("settings"),
+		portfolios:   db.Collection("portfolios"),
+		ledgerLocks:  db.Collection("ledger_locks"),
+	}
+}
+
+const (
+	// ledgerLockTTL bounds how long a crashed writer can hold a ledger lock;
+	// it outlasts the slowest guarded write, a one-minute import
+	ledgerLockTTL  = 2 * time.Minute
+	ledgerLockPoll = 50 * time.Millisecond
+)
+
+// ledgerLockKey names the lock of one portfolio's ledger
+func ledgerLockKey(userID string, portfolioID string) string {
+	return userID + "/" + models.PortfolioOf(portfolioID)
+}
+
+// withPortfolio narrows filter to portfolioID. Records without a
+// portfolio_id belong to the default portfolio.
+func withPortfolio(filter bson.M, portfolioID string) bson.M {
+	switch portfolioID {
+	case "":
+	case models.DefaultPortfolioID:
+		filter["portfolio_id"] = bson.M{"$in": bson.A{models.DefaultPortfolioID, nil}}
+	default:
+		filter["portfolio_id"] = portfolioID
 	}
+	return filter
 }
 
-func (r *MongoPortfolioRepository) ListHoldings(ctx context.Context, userID string) ([]models.Holding, error) {
+func (r *MongoPortfolioRepository) ListHoldings(ctx context.Context, userID string, portfolioID string) ([]models.Holding, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cur, err := r.holdings.Find(ctx, bson.M{"user_id": userID})
+	cur, err := r.holdings.Find(ctx, withPortfolio(bson.M{"user_id": userID}, portfolioID))
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +150,26 @@ func (r *MongoPortfolioRepository) ListHoldings(ctx context.Context, userID stri
 	return holdings, nil
 }
 
+func (r *MongoPortfolioRepository) GetHolding(ctx context.Context, id string, userID string, portfolioID string) (*models.Holding, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var holding models.Holding
+	err = r.holdings.FindOne(ctx, withPortfolio(bson.M{"_id": objID, "user_id": userID}, portfolioID)).Decode(&holding)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
 func (r *MongoPortfolioRepository) CreateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -60,7 +182,31 @@ func (r *MongoPortfolioRepository) CreateHolding(ctx context.Context, holding mo
 	return &holding, nil
 }
 
-func (r *MongoPortfolioRepository) DeleteHolding(ctx context.Context, id string, userID string) error {
+func (r *MongoPortfolioRepository) UpdateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := withPortfolio(bson.M{"_id": holding.ID, "user_id": holding.UserID}, holding.PortfolioID)
+	if holding.Version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = holding.Version
+	}
+	holding.Version++
+	res, err := r.holdings.ReplaceOne(ctx, filter, holding)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := r.GetHolding(ctx, holding.ID.Hex(), holding.UserID, holding.PortfolioID); err != nil {
+			return nil, err
+		}
+		return nil, ErrVersionConflict
+	}
+	return &holding, nil
+}
+
+func (r *MongoPortfolioRepository) DeleteHolding(ctx context.Context, id string, userID string, portfolioID string) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -69,15 +215,44 @@ func (r *MongoPortfolioRepository) DeleteHolding(ctx context.Context, id string,
 		return err
 	}
 
-	_, err = r.holdings.DeleteOne(ctx, bson.M{"_id": objID, "user_id": userID})
+	_, err = r.holdings.DeleteOne(ctx, withPortfolio(bson.M{"_id": objID, "user_id": userID}, portfolioID))
 	return err
 }
 
-func (r *MongoPortfolioRepository) ListSnapshots(ctx context.Context, userID string) ([]models.Snapshot, error) {
+func (r *MongoPortfolioRepository) ListSnapshots(ctx context.Context, userID string, portfolioID string, query SnapshotQuery) ([]models.Snapshot, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cur, err := r.history.Find(ctx, bson.M{"user_id": userID})
+	filter := withPortfolio(bson.M{"user_id": userID}, portfolioID)
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = models.ToPrimitiveDateTime(query.From)
+	}
+	if !query.To.IsZero() {
+		timestamp["$lt"] = models.ToPrimitiveDateTime(query.To)
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+	dir := 1
+	if query.Descending {
+		dir = -1
+	}
+	if query.After != nil {
+		beyond, tieBreak := "$gt", "$gt"
+		if query.Descending {
+			beyond, tieBreak = "$lt", "$lt"
+		}
+		filter["$or"] = bson.A{
+			bson.M{"timestamp": bson.M{beyond: query.After.Timestamp}},
+			bson.M{"timestamp": query.After.Timestamp, "_id": bson.M{tieBreak: query.After.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: dir}, {Key: "_id", Value: dir}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	cur, err := r.history.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +265,22 @@ func (r *MongoPortfolioRepository) ListSnapshots(ctx context.Context, userID str
 	return snapshots, nil
 }
 
+func (r *MongoPortfolioRepository) LatestSnapshot(ctx context.Context, userID string, portfolioID string) (*models.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	var snapshot models.Snapshot
+	err := r.history.FindOne(ctx, withPortfolio(bson.M{"user_id": userID}, portfolioID), opts).Decode(&snapshot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
 func (r *MongoPortfolioRepository) CreateSnapshot(ctx context.Context, snapshot models.Snapshot) (*models.Snapshot, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -101,3 +292,337 @@ func (r *MongoPortfolioRepository) CreateSnapshot(ctx context.Context, snapshot
 	snapshot.ID = res.InsertedID.(primitive.ObjectID)
 	return &snapshot, nil
 }
+
+func (r *MongoPortfolioRepository) DeleteSnapshots(ctx context.Context, userID string, portfolioID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err := r.history.DeleteMany(ctx, bson.M{"user_id": userID, "portfolio_id": portfolioID})
+	return err
+}
+
+func (r *MongoPortfolioRepository) ListPortfolioOwners(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	seen := make(map[string]struct{})
+	var owners []string
+	for _, coll := range []*mongo.Collection{r.holdings, r.transactions, r.portfolios} {
+		ids, err := coll.Distinct(ctx, "user_id", bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			userID, ok := id.(string)
+			if _, dup := seen[userID]; !ok || dup {
+				continue
+			}
+			seen[userID] = struct{}{}
+			owners = append(owners, userID)
+		}
+	}
+	return owners, nil
+}
+
+func (r *MongoPortfolioRepository) ListHeldCoins(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	seen := make(map[string]struct{})
+	var coins []string
+	for _, coll := range []*mongo.Collection{r.holdings, r.transactions} {
+		ids, err := coll.Distinct(ctx, "coin_id", bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			coinID, ok := id.(string)
+			if _, dup := seen[coinID]; !ok || dup {
+				continue
+			}
+			seen[coinID] = struct{}{}
+			coins = append(coins, coinID)
+		}
+	}
+	return coins, nil
+}
+
+func (r *MongoPortfolioRepository) ListTransactions(ctx context.Context, userID string, portfolioID string) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := r.transactions.Find(ctx, withPortfolio(bson.M{"user_id": userID}, portfolioID), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var transactions []models.Transaction
+	if err := cur.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// EachTransaction decodes from the cursor one entry at a time and, unlike
+// the list methods, has no timeout of its own: a long export is bounded by
+// ctx alone
+func (r *MongoPortfolioRepository) EachTransaction(ctx context.Context, userID string, portfolioID string, from, to time.Time, fn func(models.Transaction) error) error {
+	filter := withPortfolio(bson.M{"user_id": userID}, portfolioID)
+	timestamp := bson.M{}
+	if !from.IsZero() {
+		timestamp["$gte"] = models.ToPrimitiveDateTime(from)
+	}
+	if !to.IsZero() {
+		timestamp["$lt"] = models.ToPrimitiveDateTime(to)
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := r.transactions.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var tx models.Transaction
+		if err := cur.Decode(&tx); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (r *MongoPortfolioRepository) GetTransaction(ctx context.Context, id string, userID string) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx models.Transaction
+	err = r.transactions.FindOne(ctx, bson.M{"_id": objID, "user_id": userID}).Decode(&tx)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *MongoPortfolioRepository) CreateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.transactions.InsertOne(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.ID = res.InsertedID.(primitive.ObjectID)
+	return &tx, nil
+}
+
+func (r *MongoPortfolioRepository) UpdateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.transactions.ReplaceOne(ctx, bson.M{"_id": tx.ID, "user_id": tx.UserID}, tx)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrNotFound
+	}
+	return &tx, nil
+}
+
+func (r *MongoPortfolioRepository) DeleteTransaction(ctx context.Context, id string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.transactions.DeleteOne(ctx, bson.M{"_id": objID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ImportRecords inserts both batches, removing whatever was written if a
+// later insert fails. Standalone servers have no multi-document transactions,
+// so atomicity is best-effort compensation rather than a session.
+// LockLedger takes a lease in the ledger_locks collection. The upsert only
+// matches an expired lease, so while another writer holds it the insert
+// fails on the duplicate _id and the attempt is retried.
+func (r *MongoPortfolioRepository) LockLedger(ctx context.Context, userID string, portfolioID string) (func(), error) {
+	key := ledgerLockKey(userID, portfolioID)
+	holder := primitive.NewObjectID()
+	for {
+		now := time.Now()
+		_, err := r.ledgerLocks.UpdateOne(ctx,
+			bson.M{"_id": key, "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"holder": holder, "expires_at": now.Add(ledgerLockTTL)}},
+			options.Update().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(ledgerLockPoll):
+		}
+	}
+	return func() {
+		// Use a fresh context so the lock is released when ctx has expired
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = r.ledgerLocks.DeleteOne(releaseCtx, bson.M{"_id": key, "holder": holder})
+	}, nil
+}
+
+func (r *MongoPortfolioRepository) ImportRecords(ctx context.Context, holdings []models.Holding, transactions []models.Transaction) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	holdingDocs := make([]interface{}, len(holdings))
+	holdingIDs := make([]primitive.ObjectID, len(holdings))
+	for i, holding := range holdings {
+		holdingDocs[i] = holding
+		holdingIDs[i] = holding.ID
+	}
+	txDocs := make([]interface{}, len(transactions))
+	txIDs := make([]primitive.ObjectID, len(transactions))
+	for i, tx := range transactions {
+		txDocs[i] = tx
+		txIDs[i] = tx.ID
+	}
+
+	rollback := func() {
+		// Use a fresh context so cleanup still runs when ctx has expired
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cleanupCancel()
+		if len(holdingIDs) > 0 {
+			_, _ = r.holdings.DeleteMany(cleanupCtx, bson.M{"_id": bson.M{"$in": holdingIDs}})
+		}
+		if len(txIDs) > 0 {
+			_, _ = r.transactions.DeleteMany(cleanupCtx, bson.M{"_id": bson.M{"$in": txIDs}})
+		}
+	}
+	if len(holdingDocs) > 0 {
+		if _, err := r.holdings.InsertMany(ctx, holdingDocs); err != nil {
+			rollback()
+			return err
+		}
+	}
+	if len(txDocs) > 0 {
+		if _, err := r.transactions.InsertMany(ctx, txDocs); err != nil {
+			rollback()
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoPortfolioRepository) GetSettings(ctx context.Context, userID string) (*models.PortfolioSettings, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var settings models.PortfolioSettings
+	err := r.settings.FindOne(ctx, bson.M{"user_id": userID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *MongoPortfolioRepository) SaveSettings(ctx context.Context, settings models.PortfolioSettings) (*models.PortfolioSettings, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.settings.ReplaceOne(ctx, bson.M{"user_id": settings.UserID}, settings, opts); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *MongoPortfolioRepository) ListPortfolios(ctx context.Context, userID string) ([]models.Portfolio, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.portfolios.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var portfolios []models.Portfolio
+	if err := cur.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	return portfolios, nil
+}
+
+func (r *MongoPortfolioRepository) GetPortfolio(ctx context.Context, userID string, id string) (*models.Portfolio, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var portfolio models.Portfolio
+	err := r.portfolios.FindOne(ctx, bson.M{"user_id": userID, "portfolio_id": id}).Decode(&portfolio)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+func (r *MongoPortfolioRepository) SavePortfolio(ctx context.Context, portfolio models.Portfolio) (*models.Portfolio, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	filter := bson.M{"user_id": portfolio.UserID, "portfolio_id": portfolio.ID}
+	if _, err := r.portfolios.ReplaceOne(ctx, filter, portfolio, opts); err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+func (r *MongoPortfolioRepository) DeletePortfolio(ctx context.Context, userID string, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.portfolios.DeleteOne(ctx, bson.M{"user_id": userID, "portfolio_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
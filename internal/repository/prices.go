@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type PriceRepository interface {
+	// SavePrices stores records, replacing any existing record for the same
+	// coin and timestamp
+	SavePrices(ctx context.Context, records []models.PriceRecord) error
+	// PriceAt returns the latest record for coinID at or before t
+	PriceAt(ctx context.Context, coinID string, t time.Time) (*models.PriceRecord, error)
+	ListPrices(ctx context.Context, coinID string, from, to time.Time) ([]models.PriceRecord, error)
+}
+
+type MongoPriceRepository struct {
+	prices *mongo.Collection
+}
+
+func NewMongoPriceRepository(db *mongo.Database) *MongoPriceRepository {
+	return &MongoPriceRepository{
+		prices: db.Collection("prices"),
+	}
+}
+
+func (r *MongoPriceRepository) SavePrices(ctx context.Context, records []models.PriceRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(records))
+	for _, record := range records {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"coin_id": record.CoinID, "timestamp": record.Timestamp}).
+			SetReplacement(record).
+			SetUpsert(true))
+	}
+	_, err := r.prices.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *MongoPriceRepository) PriceAt(ctx context.Context, coinID string, t time.Time) (*models.PriceRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"coin_id": coinID, "timestamp": bson.M{"$lte": models.ToPrimitiveDateTime(t)}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+
+	var record models.PriceRecord
+	err := r.prices.FindOne(ctx, filter, opts).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *MongoPriceRepository) ListPrices(ctx context.Context, coinID string, from, to time.Time) ([]models.PriceRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"coin_id": coinID,
+		"timestamp": bson.M{
+			"$gte": models.ToPrimitiveDateTime(from),
+			"$lte": models.ToPrimitiveDateTime(to),
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	cur, err := r.prices.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var records []models.PriceRecord
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// MemoryPriceRepository keeps each coin's records sorted by timestamp
+type MemoryPriceRepository struct {
+	prices map[string][]models.PriceRecord // key: coin ID
+	mu     sync.RWMutex
+}
+
+func NewMemoryPriceRepository() *MemoryPriceRepository {
+	return &MemoryPriceRepository{
+		prices: make(map[string][]models.PriceRecord),
+	}
+}
+
+func (r *MemoryPriceRepository) SavePrices(ctx context.Context, records []models.PriceRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		if record.ID.IsZero() {
+			record.ID = primitive.NewObjectID()
+		}
+		series := r.prices[record.CoinID]
+		i := sort.Search(len(series), func(i int) bool {
+			return series[i].Timestamp >= record.Timestamp
+		})
+		if i < len(series) && series[i].Timestamp == record.Timestamp {
+			series[i] = record
+			continue
+		}
+		series = append(series, models.PriceRecord{})
+		copy(series[i+1:], series[i:])
+		series[i] = record
+		r.prices[record.CoinID] = series
+	}
+	return nil
+}
+
+func (r *MemoryPriceRepository) PriceAt(ctx context.Context, coinID string, t time.Time) (*models.PriceRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	series := r.prices[coinID]
+	ts := models.ToPrimitiveDateTime(t)
+	i := sort.Search(len(series), func(i int) bool {
+		return series[i].Timestamp > ts
+	})
+	if i == 0 {
+		return nil, ErrNotFound
+	}
+	record := series[i-1]
+	return &record, nil
+}
+
+func (r *MemoryPriceRepository) ListPrices(ctx context.Context, coinID string, from, to time.Time) ([]models.PriceRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fromTS, toTS := models.ToPrimitiveDateTime(from), models.ToPrimitiveDateTime(to)
+	var result []models.PriceRecord
+	for _, record := range r.prices[coinID] {
+		if record.Timestamp >= fromTS && record.Timestamp <= toTS {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type MemoryUserRepository struct {
+	users   map[string]models.User // key: user ID
+	byEmail map[string]string      // email -> user ID
+	mu      sync.RWMutex
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:   make(map[string]models.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byEmail[user.Email]; exists {
+		return nil, ErrConflict
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	r.users[user.ID.Hex()] = user
+	r.byEmail[user.Email] = user.ID.Hex()
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, exists := r.byEmail[email]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	user := r.users[id]
+	return &user, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type WatchlistRepository interface {
+	CreateWatchlist(ctx context.Context, list models.Watchlist) (*models.Watchlist, error)
+	ListWatchlists(ctx context.Context, userID string) ([]models.Watchlist, error)
+	GetWatchlist(ctx context.Context, id string, userID string) (*models.Watchlist, error)
+	UpdateWatchlist(ctx context.Context, list models.Watchlist) error
+	DeleteWatchlist(ctx context.Context, id string, userID string) error
+}
+
+type MongoWatchlistRepository struct {
+	watchlists *mongo.Collection
+}
+
+func NewMongoWatchlistRepository(db *mongo.Database) *MongoWatchlistRepository {
+	return &MongoWatchlistRepository{
+		watchlists: db.Collection("watchlists"),
+	}
+}
+
+func (r *MongoWatchlistRepository) CreateWatchlist(ctx context.Context, list models.Watchlist) (*models.Watchlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.watchlists.InsertOne(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	list.ID = res.InsertedID.(primitive.ObjectID)
+	return &list, nil
+}
+
+func (r *MongoWatchlistRepository) ListWatchlists(ctx context.Context, userID string) ([]models.Watchlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.watchlists.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var lists []models.Watchlist
+	if err := cur.All(ctx, &lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (r *MongoWatchlistRepository) GetWatchlist(ctx context.Context, id string, userID string) (*models.Watchlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var list models.Watchlist
+	err = r.watchlists.FindOne(ctx, bson.M{"_id": objID, "user_id": userID}).Decode(&list)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *MongoWatchlistRepository) UpdateWatchlist(ctx context.Context, list models.Watchlist) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.watchlists.ReplaceOne(ctx, bson.M{"_id": list.ID, "user_id": list.UserID}, list)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoWatchlistRepository) DeleteWatchlist(ctx context.Context, id string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res, err := r.watchlists.DeleteOne(ctx, bson.M{"_id": objID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
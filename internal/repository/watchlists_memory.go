@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type MemoryWatchlistRepository struct {
+	watchlists map[string]models.Watchlist // key: watchlist ID
+	mu         sync.RWMutex
+}
+
+func NewMemoryWatchlistRepository() *MemoryWatchlistRepository {
+	return &MemoryWatchlistRepository{
+		watchlists: make(map[string]models.Watchlist),
+	}
+}
+
+// copyWatchlist detaches the items slice so callers cannot mutate stored state
+func copyWatchlist(list models.Watchlist) models.Watchlist {
+	list.Items = append([]models.WatchlistItem(nil), list.Items...)
+	return list
+}
+
+func (r *MemoryWatchlistRepository) CreateWatchlist(ctx context.Context, list models.Watchlist) (*models.Watchlist, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if list.ID.IsZero() {
+		list.ID = primitive.NewObjectID()
+	}
+	r.watchlists[list.ID.Hex()] = copyWatchlist(list)
+	return &list, nil
+}
+
+func (r *MemoryWatchlistRepository) ListWatchlists(ctx context.Context, userID string) ([]models.Watchlist, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.Watchlist
+	for _, list := range r.watchlists {
+		if list.UserID == userID {
+			result = append(result, copyWatchlist(list))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt < result[j].CreatedAt
+		}
+		return bytes.Compare(result[i].ID[:], result[j].ID[:]) < 0
+	})
+	return result, nil
+}
+
+func (r *MemoryWatchlistRepository) GetWatchlist(ctx context.Context, id string, userID string) (*models.Watchlist, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, exists := r.watchlists[id]
+	if !exists || list.UserID != userID {
+		return nil, ErrNotFound
+	}
+	list = copyWatchlist(list)
+	return &list, nil
+}
+
+func (r *MemoryWatchlistRepository) UpdateWatchlist(ctx context.Context, list models.Watchlist) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.watchlists[list.ID.Hex()]
+	if !exists || existing.UserID != list.UserID {
+		return ErrNotFound
+	}
+	r.watchlists[list.ID.Hex()] = copyWatchlist(list)
+	return nil
+}
+
+func (r *MemoryWatchlistRepository) DeleteWatchlist(ctx context.Context, id string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.watchlists[id]
+	if !exists || list.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.watchlists, id)
+	return nil
+}
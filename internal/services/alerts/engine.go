@@ -0,0 +1,339 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/market"
+)
+
+// evaluationTimeout bounds one pass over every enabled rule
+const evaluationTimeout = time.Minute
+
+// handleUpdate runs the rule engine after a market refresh. Listeners must not
+// block, so evaluation happens on its own goroutine; updates arriving while a
+// pass is running are folded into a single follow-up pass.
+func (s *Service) handleUpdate(data *market.MarketData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest[data.Currency] = data
+	if s.running {
+		s.pending = true
+		return
+	}
+	s.running = true
+	s.done = make(chan struct{})
+	go s.evaluateLoop(s.done)
+}
+
+func (s *Service) evaluateLoop(done chan struct{}) {
+	defer close(done)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+		if err := s.Evaluate(ctx, time.Now()); err != nil {
+			log.Printf("alerts: evaluation failed: %v", err)
+		}
+		cancel()
+
+		s.mu.Lock()
+		if !s.pending {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		s.pending = false
+		s.mu.Unlock()
+	}
+}
+
+// Wait blocks until an in-flight evaluation and the webhooks it queued
+// finish, or ctx expires
+func (s *Service) Wait(ctx context.Context) error {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	delivered := make(chan struct{})
+	go func() {
+		s.deliveries.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Evaluate checks every enabled rule against current prices and fires those
+// whose trigger mode allows it. Rules whose value cannot be determined are
+// skipped and keep their state.
+func (s *Service) Evaluate(ctx context.Context, now time.Time) error {
+	rules, err := s.repo.ListEnabledAlertRules(ctx)
+	if err != nil {
+		return err
+	}
+	q := s.newQuotes(rules)
+	for _, rule := range rules {
+		value, ok, err := q.value(ctx, rule)
+		if err != nil {
+			log.Printf("alerts: rule %s: %v", rule.ID.Hex(), err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.apply(ctx, rule, value, now)
+	}
+	return nil
+}
+
+func matches(rule models.AlertRule, value float64) bool {
+	switch rule.Condition {
+	case models.AlertPriceAbove, models.AlertPortfolioAbove:
+		return value > rule.Threshold
+	case models.AlertPriceBelow, models.AlertPortfolioBelow:
+		return value < rule.Threshold
+	case models.AlertChange24h:
+		return math.Abs(value) >= rule.Threshold
+	}
+	return false
+}
+
+// shouldFire applies the trigger mode to a rule whose condition holds
+func shouldFire(rule models.AlertRule, now time.Time) bool {
+	switch rule.Mode {
+	case models.AlertRecurring:
+		return !rule.Matching
+	case models.AlertCooldown:
+		cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
+		return rule.LastTriggeredAt == nil || now.Sub(rule.LastTriggeredAt.Time()) >= cooldown
+	}
+	return true
+}
+
+func (s *Service) apply(ctx context.Context, rule models.AlertRule, value float64, now time.Time) {
+	matching := matches(rule, value)
+	fire := matching && shouldFire(rule, now)
+	if !fire && matching == rule.Matching {
+		return
+	}
+	rule.Matching = matching
+	if fire {
+		triggeredAt := models.ToPrimitiveDateTime(now)
+		rule.LastTriggeredAt = &triggeredAt
+		if rule.Mode == models.AlertOnce {
+			rule.Enabled = false
+		}
+	}
+	// Store the new state before notifying so a slow channel cannot cause a
+	// second delivery on the next pass. A rule edited or disabled since this
+	// pass read it is left alone and evaluated afresh next time.
+	err := s.repo.UpdateAlertState(ctx, rule)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return
+	}
+	if err != nil {
+		log.Printf("alerts: rule %s: save state: %v", rule.ID.Hex(), err)
+		return
+	}
+	if fire {
+		s.fire(ctx, rule, value, now)
+	}
+}
+
+// Delivery statuses other than an error message
+const (
+	deliveryOK     = "ok"
+	deliveryQueued = "queued"
+)
+
+// fire delivers the alert on each of the rule's channels and records the
+// outcome in the rule's history. Webhooks are sent in the background.
+func (s *Service) fire(ctx context.Context, rule models.AlertRule, value float64, now time.Time) {
+	event := models.AlertEvent{
+		ID:          primitive.NewObjectID(),
+		RuleID:      rule.ID,
+		UserID:      rule.UserID,
+		Message:     describe(rule, value),
+		Value:       value,
+		Threshold:   rule.Threshold,
+		Currency:    rule.Currency,
+		TriggeredAt: models.ToPrimitiveDateTime(now),
+		Delivery:    make(map[string]string, len(rule.Channels)),
+	}
+	webhook := false
+	for _, ch := range rule.Channels {
+		notifier, ok := s.notifiers[ch]
+		if !ok {
+			event.Delivery[ch] = "unknown channel"
+			continue
+		}
+		if ch == models.ChannelWebhook {
+			webhook = true
+			event.Delivery[ch] = deliveryQueued
+			continue
+		}
+		if err := notifier.Notify(ctx, rule, event); err != nil {
+			log.Printf("alerts: rule %s: %s delivery: %v", rule.ID.Hex(), ch, err)
+			event.Delivery[ch] = err.Error()
+			continue
+		}
+		event.Delivery[ch] = deliveryOK
+	}
+	if _, err := s.repo.CreateAlertEvent(ctx, event); err != nil {
+		log.Printf("alerts: rule %s: record event: %v", rule.ID.Hex(), err)
+	}
+	if webhook {
+		s.queueWebhook(rule, event)
+	}
+}
+
+func describe(rule models.AlertRule, value float64) string {
+	currency := strings.ToUpper(rule.Currency)
+	switch rule.Condition {
+	case models.AlertPriceAbove:
+		return fmt.Sprintf("%s is at %.2f %s, above %.2f", rule.CoinID, value, currency, rule.Threshold)
+	case models.AlertPriceBelow:
+		return fmt.Sprintf("%s is at %.2f %s, below %.2f", rule.CoinID, value, currency, rule.Threshold)
+	case models.AlertChange24h:
+		return fmt.Sprintf("%s moved %+.2f%% in 24h, beyond %.2f%%", rule.CoinID, value, rule.Threshold)
+	case models.AlertPortfolioAbove:
+		return fmt.Sprintf("Portfolio %s is worth %.2f %s, above %.2f", rule.PortfolioID, value, currency, rule.Threshold)
+	case models.AlertPortfolioBelow:
+		return fmt.Sprintf("Portfolio %s is worth %.2f %s, below %.2f", rule.PortfolioID, value, currency, rule.Threshold)
+	}
+	return ""
+}
+
+// quotes resolves rule values for one evaluation pass, fetching each
+// currency's prices and each portfolio's valuation at most once
+type quotes struct {
+	s          *Service
+	latest     map[string]*market.MarketData
+	coins      map[string][]string           // key: currency
+	prices     map[string]map[string]float64 // key: currency, then coin
+	portfolios map[string]*float64           // key: user/portfolio/currency; nil when unavailable
+}
+
+func (s *Service) newQuotes(rules []models.AlertRule) *quotes {
+	s.mu.Lock()
+	latest := make(map[string]*market.MarketData, len(s.latest))
+	for currency, data := range s.latest {
+		latest[currency] = data
+	}
+	s.mu.Unlock()
+
+	coins := make(map[string][]string)
+	for _, rule := range rules {
+		if rule.Condition == models.AlertPriceAbove || rule.Condition == models.AlertPriceBelow {
+			coins[rule.Currency] = append(coins[rule.Currency], rule.CoinID)
+		}
+	}
+	return &quotes{
+		s:          s,
+		latest:     latest,
+		coins:      coins,
+		prices:     make(map[string]map[string]float64),
+		portfolios: make(map[string]*float64),
+	}
+}
+
+// value returns the figure a rule's condition is tested against; ok is false
+// when it is not available this pass
+func (q *quotes) value(ctx context.Context, rule models.AlertRule) (float64, bool, error) {
+	switch rule.Condition {
+	case models.AlertPriceAbove, models.AlertPriceBelow:
+		prices, err := q.pricesIn(rule.Currency)
+		if err != nil {
+			return 0, false, err
+		}
+		price, ok := prices[rule.CoinID]
+		return price, ok, nil
+	case models.AlertChange24h:
+		change, ok := q.change24h(rule.CoinID, rule.Currency)
+		return change, ok, nil
+	case models.AlertPortfolioAbove, models.AlertPortfolioBelow:
+		return q.portfolioValue(ctx, rule)
+	}
+	return 0, false, nil
+}
+
+func (q *quotes) pricesIn(currency string) (map[string]float64, error) {
+	if prices, ok := q.prices[currency]; ok {
+		return prices, nil
+	}
+	prices, err := q.s.marketService.GetPrices(q.coins[currency], currency)
+	if err != nil {
+		return nil, err
+	}
+	q.prices[currency] = prices
+	return prices, nil
+}
+
+// change24h reads the 24h change from the latest top-N payload, preferring the
+// rule's currency. Coins outside the top N carry no change and are skipped.
+func (q *quotes) change24h(coinID string, currency string) (float64, bool) {
+	find := func(data *market.MarketData) (float64, bool) {
+		for _, coin := range data.Coins {
+			if coin.ID == coinID {
+				return coin.PriceChangePercentage24h, true
+			}
+		}
+		return 0, false
+	}
+	if data, ok := q.latest[currency]; ok {
+		if change, found := find(data); found {
+			return change, true
+		}
+	}
+	for _, data := range q.latest {
+		if change, found := find(data); found {
+			return change, true
+		}
+	}
+	return 0, false
+}
+
+// portfolioValue totals a portfolio in the rule's currency. Empty portfolios
+// and ones with an unpriced holding are skipped rather than reported low.
+func (q *quotes) portfolioValue(ctx context.Context, rule models.AlertRule) (float64, bool, error) {
+	key := rule.UserID + "/" + rule.PortfolioID + "/" + rule.Currency
+	if total, ok := q.portfolios[key]; ok {
+		if total == nil {
+			return 0, false, nil
+		}
+		return *total, true, nil
+	}
+	holdings, total, err := q.s.portfolioService.GetHoldingsWithValue(ctx, rule.UserID, rule.PortfolioID, rule.Currency)
+	if err != nil {
+		return 0, false, err
+	}
+	q.portfolios[key] = nil
+	if len(holdings) == 0 {
+		return 0, false, nil
+	}
+	for _, h := range holdings {
+		if h.PriceUnavailable {
+			return 0, false, nil
+		}
+	}
+	q.portfolios[key] = &total
+	return total, true, nil
+}
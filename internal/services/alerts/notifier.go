@@ -0,0 +1,216 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+)
+
+// Notifier delivers a fired alert over one channel
+type Notifier interface {
+	Notify(ctx context.Context, rule models.AlertRule, event models.AlertEvent) error
+}
+
+// InboxNotifier stores alerts as in-app messages
+type InboxNotifier struct {
+	repo repository.AlertRepository
+}
+
+func NewInboxNotifier(repo repository.AlertRepository) *InboxNotifier {
+	return &InboxNotifier{repo: repo}
+}
+
+func (n *InboxNotifier) Notify(ctx context.Context, rule models.AlertRule, event models.AlertEvent) error {
+	_, err := n.repo.CreateInboxMessage(ctx, models.InboxMessage{
+		UserID:    event.UserID,
+		EventID:   event.ID,
+		Title:     ruleTitle(rule),
+		Body:      event.Message,
+		CreatedAt: event.TriggeredAt,
+	})
+	return err
+}
+
+// WebhookNotifier POSTs alerts as JSON to the rule's webhook URL. It only
+// connects to public addresses and does not follow redirects, so a rule
+// cannot be used to reach services inside the network.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivateAddress}
+	return &WebhookNotifier{client: &http.Client{
+		Timeout: timeout,
+		// No Proxy: a proxy would dial on our behalf, past the address check
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// reservedPrefixes are non-public ranges the netip predicates do not cover
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// publicAddress reports whether webhooks may be delivered to addr
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// refusePrivateAddress is the dialer's last check, made on the address
+// actually being dialed so DNS cannot be rebound after validation
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !publicAddress(addr) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// checkWebhookHost resolves host and rejects it unless every address is public
+func checkWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("webhook host %s does not resolve", host)
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errPrivateAddress, host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+type webhookPayload struct {
+	Title string            `json:"title"`
+	Rule  webhookRule       `json:"rule"`
+	Event models.AlertEvent `json:"event"`
+}
+
+// webhookRule is the part of a rule a receiver needs; the URL itself is left out
+type webhookRule struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Condition   models.AlertCondition `json:"condition"`
+	CoinID      string                `json:"coinId,omitempty"`
+	PortfolioID string                `json:"portfolioId,omitempty"`
+	Threshold   float64               `json:"threshold"`
+	Currency    string                `json:"currency"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, rule models.AlertRule, event models.AlertEvent) error {
+	body, err := json.Marshal(webhookPayload{
+		Title: ruleTitle(rule),
+		Rule: webhookRule{
+			ID:          rule.ID.Hex(),
+			Name:        rule.Name,
+			Condition:   rule.Condition,
+			CoinID:      rule.CoinID,
+			PortfolioID: rule.PortfolioID,
+			Threshold:   rule.Threshold,
+			Currency:    rule.Currency,
+		},
+		Event: event,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookWorkers and webhookQueueSize bound how many webhooks are sent at
+// once and how many may wait
+const (
+	webhookWorkers   = 4
+	webhookQueueSize = 100
+)
+
+type webhookDelivery struct {
+	rule  models.AlertRule
+	event models.AlertEvent
+}
+
+// queueWebhook hands an event to the delivery workers, so a slow receiver
+// holds up neither rule evaluation nor other deliveries. The outcome replaces
+// the event's "queued" delivery status.
+func (s *Service) queueWebhook(rule models.AlertRule, event models.AlertEvent) {
+	s.deliveries.Add(1)
+	select {
+	case s.webhooks <- webhookDelivery{rule: rule, event: event}:
+	default:
+		s.deliveries.Done()
+		log.Printf("alerts: rule %s: webhook queue full, dropping delivery", rule.ID.Hex())
+		s.recordDelivery(event, "dropped: delivery queue full")
+	}
+}
+
+func (s *Service) deliverWebhooks() {
+	timeout := time.Duration(s.cfg.AlertWebhookTimeoutSeconds) * time.Second
+	for d := range s.webhooks {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		status := deliveryOK
+		if err := s.notifiers[models.ChannelWebhook].Notify(ctx, d.rule, d.event); err != nil {
+			log.Printf("alerts: rule %s: webhook delivery: %v", d.rule.ID.Hex(), err)
+			status = err.Error()
+		}
+		cancel()
+		s.recordDelivery(d.event, status)
+		s.deliveries.Done()
+	}
+}
+
+func (s *Service) recordDelivery(event models.AlertEvent, status string) {
+	if err := s.repo.SetAlertDelivery(context.Background(), event.ID, models.ChannelWebhook, status); err != nil {
+		log.Printf("alerts: event %s: record webhook delivery: %v", event.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,230 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/market"
+	"github.com/faisal/crypto/backend/internal/services/portfolio"
+)
+
+var ErrNotFound = repository.ErrNotFound
+
+type Service struct {
+	cfg              *config.Config
+	repo             repository.AlertRepository
+	marketService    *market.Service
+	portfolioService *portfolio.Service
+	notifiers        map[string]Notifier
+	webhooks         chan webhookDelivery
+	deliveries       sync.WaitGroup
+
+	mu      sync.Mutex
+	latest  map[string]*market.MarketData // key: currency
+	running bool
+	pending bool
+	done    chan struct{}
+}
+
+// NewService creates an alerts service with in-memory storage (for development)
+func NewService(cfg *config.Config, marketService *market.Service, portfolioService *portfolio.Service) *Service {
+	return newService(cfg, repository.NewMemoryAlertRepository(), marketService, portfolioService)
+}
+
+// NewServiceWithMongo creates an alerts service backed by MongoDB
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client, marketService *market.Service, portfolioService *portfolio.Service) *Service {
+	return newService(cfg, repository.NewMongoAlertRepository(client.Database(cfg.MongoDBName)), marketService, portfolioService)
+}
+
+// newService subscribes the rule engine to market refreshes, so it must be
+// called before the market poller starts
+func newService(cfg *config.Config, repo repository.AlertRepository, marketService *market.Service, portfolioService *portfolio.Service) *Service {
+	s := &Service{
+		cfg:              cfg,
+		repo:             repo,
+		marketService:    marketService,
+		portfolioService: portfolioService,
+		notifiers: map[string]Notifier{
+			models.ChannelInbox:   NewInboxNotifier(repo),
+			models.ChannelWebhook: NewWebhookNotifier(time.Duration(cfg.AlertWebhookTimeoutSeconds) * time.Second),
+		},
+		webhooks: make(chan webhookDelivery, webhookQueueSize),
+		latest:   make(map[string]*market.MarketData),
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		go s.deliverWebhooks()
+	}
+	marketService.OnUpdate(s.handleUpdate)
+	return s
+}
+
+func (s *Service) ListRules(ctx context.Context, userID string) ([]models.AlertRule, error) {
+	return s.repo.ListAlertRules(ctx, userID)
+}
+
+func (s *Service) GetRule(ctx context.Context, id string, userID string) (*models.AlertRule, error) {
+	return s.repo.GetAlertRule(ctx, id, userID)
+}
+
+func (s *Service) CreateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	if err := s.validateRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	rule.CreatedAt = models.ToPrimitiveDateTime(time.Now())
+	rule.UpdatedAt = rule.CreatedAt
+	rule.LastTriggeredAt = nil
+	rule.Matching = false
+	return s.repo.CreateAlertRule(ctx, rule)
+}
+
+// UpdateRule replaces a rule's definition. Evaluation state is kept unless
+// what the rule watches changed.
+func (s *Service) UpdateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	existing, err := s.repo.GetAlertRule(ctx, rule.ID.Hex(), rule.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = models.ToPrimitiveDateTime(time.Now())
+	rule.LastTriggeredAt = existing.LastTriggeredAt
+	rule.Matching = existing.Matching && sameCriteria(*existing, rule)
+	if err := s.repo.UpdateAlertRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func sameCriteria(a, b models.AlertRule) bool {
+	return a.Condition == b.Condition && a.CoinID == b.CoinID && a.PortfolioID == b.PortfolioID &&
+		a.Threshold == b.Threshold && a.Currency == b.Currency
+}
+
+func (s *Service) DeleteRule(ctx context.Context, id string, userID string) error {
+	return s.repo.DeleteAlertRule(ctx, id, userID)
+}
+
+// ListEvents returns fired alerts newest first; an empty ruleID lists every rule,
+// including ones since deleted
+func (s *Service) ListEvents(ctx context.Context, userID string, ruleID string, limit int) ([]models.AlertEvent, error) {
+	return s.repo.ListAlertEvents(ctx, userID, ruleID, limit)
+}
+
+func (s *Service) ListInbox(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.InboxMessage, error) {
+	return s.repo.ListInboxMessages(ctx, userID, unreadOnly, limit)
+}
+
+func (s *Service) MarkInboxRead(ctx context.Context, id string, userID string) error {
+	return s.repo.MarkInboxMessageRead(ctx, id, userID, time.Now())
+}
+
+func (s *Service) validateRule(ctx context.Context, rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.UserID == "" || !rule.Condition.Valid() {
+		return errors.New("invalid alert condition")
+	}
+	if rule.Mode == "" {
+		rule.Mode = models.AlertRecurring
+	}
+	if !rule.Mode.Valid() {
+		return errors.New("invalid alert mode")
+	}
+	currency, err := s.marketService.NormalizeCurrency(rule.Currency)
+	if err != nil {
+		return err
+	}
+	rule.Currency = currency
+
+	if rule.Condition.OnPortfolio() {
+		rule.CoinID = ""
+		rule.PortfolioID = models.PortfolioOf(rule.PortfolioID)
+		if _, err := s.portfolioService.GetPortfolio(ctx, rule.UserID, rule.PortfolioID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return errors.New("unknown portfolio")
+			}
+			return err
+		}
+		if rule.Threshold < 0 {
+			return errors.New("threshold must not be negative")
+		}
+	} else {
+		rule.PortfolioID = ""
+		rule.CoinID = strings.ToLower(strings.TrimSpace(rule.CoinID))
+		if rule.CoinID == "" {
+			return errors.New("coinId is required")
+		}
+		if rule.Threshold <= 0 {
+			return errors.New("threshold must be positive")
+		}
+	}
+
+	if rule.Mode != models.AlertCooldown {
+		rule.CooldownMinutes = 0
+	} else if rule.CooldownMinutes < 0 {
+		return errors.New("cooldownMinutes must not be negative")
+	} else if rule.CooldownMinutes == 0 {
+		rule.CooldownMinutes = s.cfg.AlertCooldownMinutes
+	}
+
+	channels, err := s.validateChannels(ctx, rule)
+	if err != nil {
+		return err
+	}
+	rule.Channels = channels
+	return nil
+}
+
+// validateChannels defaults to the inbox, drops duplicates and checks the
+// webhook URL when the webhook channel is used. The webhook host must resolve
+// to public addresses only; the notifier checks again when it connects.
+func (s *Service) validateChannels(ctx context.Context, rule *models.AlertRule) ([]string, error) {
+	if len(rule.Channels) == 0 {
+		rule.Channels = []string{models.ChannelInbox}
+	}
+	var channels []string
+	seen := make(map[string]bool)
+	for _, ch := range rule.Channels {
+		ch = strings.ToLower(strings.TrimSpace(ch))
+		if _, ok := s.notifiers[ch]; !ok {
+			return nil, errors.New("unknown channel " + ch)
+		}
+		if !seen[ch] {
+			seen[ch] = true
+			channels = append(channels, ch)
+		}
+	}
+	if !seen[models.ChannelWebhook] {
+		rule.WebhookURL = ""
+		return channels, nil
+	}
+	u, err := url.Parse(strings.TrimSpace(rule.WebhookURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("webhook channel needs an absolute http(s) webhookUrl")
+	}
+	if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	rule.WebhookURL = u.String()
+	return channels, nil
+}
+
+func ruleTitle(rule models.AlertRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	if rule.Condition.OnPortfolio() {
+		return "Portfolio alert"
+	}
+	return "Price alert"
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email already registered")
+)
+
+// minPasswordLength is the shortest password accepted at registration
+const minPasswordLength = 8
+
+type Service struct {
+	cfg     *config.Config
+	users   repository.UserRepository
+	apiKeys repository.APIKeyRepository
+}
+
+// NewService creates an auth service with in-memory storage (for development)
+func NewService(cfg *config.Config) *Service {
+	return &Service{
+		cfg:     cfg,
+		users:   repository.NewMemoryUserRepository(),
+		apiKeys: repository.NewMemoryAPIKeyRepository(),
+	}
+}
+
+// NewServiceWithMongo creates an auth service backed by MongoDB
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client) *Service {
+	db := client.Database(cfg.MongoDBName)
+	return &Service{
+		cfg:     cfg,
+		users:   repository.NewMongoUserRepository(db),
+		apiKeys: repository.NewMongoAPIKeyRepository(db),
+	}
+}
+
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	AccessExpiresAt  time.Time `json:"accessExpiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+func (s *Service) Register(ctx context.Context, email, password string) (*models.User, *TokenPair, error) {
+	email = normalizeEmail(email)
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, nil, errors.New("invalid email")
+	}
+	if len(password) < minPasswordLength {
+		return nil, nil, errors.New("password must be at least 8 characters")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.users.CreateUser(ctx, models.User{
+		Email:        email,
+		PasswordHash: string(hash),
+		CreatedAt:    models.ToPrimitiveDateTime(time.Now()),
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issue(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+func (s *Service) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	user, err := s.users.GetUserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issue(user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := parseToken(s.secret(), refreshToken, TokenRefresh)
+	if err != nil {
+		return nil, err
+	}
+	// The account may have been removed since the token was issued
+	user, err := s.users.GetUserByID(ctx, claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return s.issue(user)
+}
+
+// Authenticate validates an access token and returns the user ID it was issued to
+func (s *Service) Authenticate(accessToken string) (string, error) {
+	claims, err := parseToken(s.secret(), accessToken, TokenAccess)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return s.users.GetUserByID(ctx, userID)
+}
+
+// isAdmin reports whether email is on the ADMIN_EMAILS allowlist
+func (s *Service) isAdmin(email string) bool {
+	return slices.Contains(s.cfg.AdminEmails, normalizeEmail(email))
+}
+
+// issue signs a token pair for user. The admin flag is fixed in the access
+// token, so allowlist changes apply from the next refresh.
+func (s *Service) issue(user *models.User) (*TokenPair, error) {
+	userID := user.ID.Hex()
+	now := time.Now()
+	accessExp := now.Add(time.Duration(s.cfg.AccessTokenTTLMinutes) * time.Minute)
+	refreshExp := now.Add(time.Duration(s.cfg.RefreshTokenTTLHours) * time.Hour)
+
+	access, err := signToken(s.secret(), Claims{
+		Subject:   userID,
+		Type:      TokenAccess,
+		Admin:     s.isAdmin(user.Email),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: accessExp.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := signToken(s.secret(), Claims{
+		Subject:   userID,
+		Type:      TokenRefresh,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: refreshExp.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExp.UTC(),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: refreshExp.UTC(),
+	}, nil
+}
+
+func (s *Service) secret() []byte {
+	return []byte(s.cfg.JWTSecret)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// indexEntry keeps lower-cased copies of the searchable fields
+type indexEntry struct {
+	coin   models.Coin
+	id     string
+	symbol string
+	name   string
+}
+
+func buildIndex(coins []models.Coin) []indexEntry {
+	index := make([]indexEntry, len(coins))
+	for i, coin := range coins {
+		index[i] = indexEntry{
+			coin:   coin,
+			id:     coin.ID,
+			symbol: coin.Symbol,
+			name:   strings.ToLower(coin.Name),
+		}
+	}
+	return index
+}
+
+// Search ranks catalog coins against q: exact ID, symbol or name matches
+// first, then prefix and substring matches, then IDs, symbols and names
+// within a small edit distance so typos still find the coin.
+func (s *Service) Search(q string, limit int) []models.Coin {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" || limit <= 0 {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type hit struct {
+		entry *indexEntry
+		score int
+	}
+	var hits []hit
+	for i := range s.index {
+		if score := matchScore(&s.index[i], q); score > 0 {
+			hits = append(hits, hit{&s.index[i], score})
+		}
+	}
+	// Among equal scores prefer shorter names, which tend to be the
+	// canonical coin rather than a wrapped or bridged variant
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.entry.name) != len(b.entry.name) {
+			return len(a.entry.name) < len(b.entry.name)
+		}
+		return a.entry.id < b.entry.id
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	coins := make([]models.Coin, len(hits))
+	for i, h := range hits {
+		coins[i] = h.entry.coin
+	}
+	return coins
+}
+
+func matchScore(e *indexEntry, q string) int {
+	switch {
+	case e.id == q:
+		return 100
+	case e.symbol == q:
+		return 95
+	case e.name == q:
+		return 90
+	case strings.HasPrefix(e.id, q), strings.HasPrefix(e.name, q):
+		return 80
+	case strings.HasPrefix(e.symbol, q):
+		return 75
+	case strings.Contains(e.id, q), strings.Contains(e.name, q):
+		return 60
+	}
+	// Very short queries match too much by edit distance to be useful
+	if len(q) < 4 {
+		return 0
+	}
+	maxDist := 1
+	if len(q) >= 7 {
+		maxDist = 2
+	}
+	best := maxDist + 1
+	for _, field := range [...]string{e.id, e.symbol, e.name} {
+		if d := boundedDistance(field, q, maxDist); d < best {
+			best = d
+		}
+	}
+	if best > maxDist {
+		return 0
+	}
+	return 40 - 10*best
+}
+
+// boundedDistance returns the Levenshtein distance between a and b, or
+// bound+1 once it is known to exceed bound
+func boundedDistance(a, b string, bound int) int {
+	if diff := len(a) - len(b); diff > bound || -diff > bound {
+		return bound + 1
+	}
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > bound {
+			return bound + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
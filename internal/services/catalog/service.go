@@ -0,0 +1,314 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/market"
+)
+
+// syncRetryDelay is how soon a failed sync is retried, capped at the sync interval
+const syncRetryDelay = 5 * time.Minute
+
+var (
+	ErrUnknownCoin = errors.New("unknown coin")
+	// ErrCatalogUnavailable is returned while no catalog has been synced or
+	// loaded, so coin IDs cannot be checked either way
+	ErrCatalogUnavailable = errors.New("coin catalog is not loaded yet")
+	ErrAmbiguousSymbol    = errors.New("ambiguous symbol")
+)
+
+type Service struct {
+	cfg           *config.Config
+	repo          repository.CoinRepository
+	marketService *market.Service
+
+	mu       sync.RWMutex
+	coins    map[string]models.Coin
+	bySymbol map[string][]string // symbol -> coin IDs
+	index    []indexEntry
+	syncedAt time.Time
+
+	schedMu sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewService creates a catalog service with in-memory storage (for development)
+func NewService(cfg *config.Config, marketService *market.Service) *Service {
+	return &Service{
+		cfg:           cfg,
+		repo:          repository.NewMemoryCoinRepository(),
+		marketService: marketService,
+	}
+}
+
+// NewServiceWithMongo creates a catalog service backed by MongoDB
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client, marketService *market.Service) *Service {
+	return &Service{
+		cfg:           cfg,
+		repo:          repository.NewMongoCoinRepository(client.Database(cfg.MongoDBName)),
+		marketService: marketService,
+	}
+}
+
+// Start loads the stored catalog and then keeps it in sync with the upstream
+// coin list in the background. A stored catalog younger than the sync
+// interval is used as-is until it is due.
+func (s *Service) Start() {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.loop(ctx, s.done)
+}
+
+// Stop halts background syncing, waiting for an in-flight sync
+func (s *Service) Stop(ctx context.Context) error {
+	s.schedMu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.schedMu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	if err := s.load(ctx); err != nil {
+		log.Printf("catalog: load stored coins: %v", err)
+	}
+	interval := time.Duration(s.cfg.CoinCatalogSyncHours) * time.Hour
+	if interval <= 0 {
+		return
+	}
+	retry := syncRetryDelay
+	if retry > interval {
+		retry = interval
+	}
+
+	delay := interval - time.Since(s.SyncedAt())
+	for {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		delay = interval
+		if err := s.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("catalog: sync failed: %v", err)
+			delay = retry
+		}
+	}
+}
+
+// load fills the in-memory catalog from storage
+func (s *Service) load(ctx context.Context) error {
+	coins, err := s.repo.ListCoins(ctx)
+	if err != nil || len(coins) == 0 {
+		return err
+	}
+	var syncedAt time.Time
+	for _, coin := range coins {
+		if t := coin.SyncedAt.Time(); t.After(syncedAt) {
+			syncedAt = t
+		}
+	}
+	s.setCoins(coins, syncedAt)
+	return nil
+}
+
+// Sync replaces the catalog with the upstream coin list. An empty list is
+// treated as an upstream fault and leaves the current catalog in place.
+func (s *Service) Sync(ctx context.Context) error {
+	infos, err := s.marketService.CoinList(ctx)
+	if err != nil {
+		return err
+	}
+	coins := make([]models.Coin, 0, len(infos))
+	seen := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		id := strings.ToLower(strings.TrimSpace(info.ID))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		coins = append(coins, models.Coin{
+			ID:        id,
+			Symbol:    strings.ToLower(strings.TrimSpace(info.Symbol)),
+			Name:      strings.TrimSpace(info.Name),
+			Platforms: info.Platforms,
+		})
+	}
+	if len(coins) == 0 {
+		return errors.New("upstream returned an empty coin list")
+	}
+	now := time.Now()
+	if err := s.repo.ReplaceCoins(ctx, coins, now); err != nil {
+		return err
+	}
+	stamp := models.ToPrimitiveDateTime(now)
+	for i := range coins {
+		coins[i].SyncedAt = stamp
+	}
+	s.setCoins(coins, now)
+	log.Printf("catalog: synced %d coins", len(coins))
+	return nil
+}
+
+func (s *Service) setCoins(coins []models.Coin, syncedAt time.Time) {
+	byID := make(map[string]models.Coin, len(coins))
+	bySymbol := make(map[string][]string)
+	for _, coin := range coins {
+		byID[coin.ID] = coin
+		if coin.Symbol != "" {
+			bySymbol[coin.Symbol] = append(bySymbol[coin.Symbol], coin.ID)
+		}
+	}
+	index := buildIndex(coins)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coins = byID
+	s.bySymbol = bySymbol
+	s.index = index
+	s.syncedAt = syncedAt
+}
+
+// SyncedAt reports when the loaded catalog was fetched upstream; zero when none is loaded
+func (s *Service) SyncedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.syncedAt
+}
+
+// Count reports the number of coins in the loaded catalog
+func (s *Service) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.coins)
+}
+
+func (s *Service) Get(coinID string) (*models.Coin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.coins) == 0 {
+		return nil, ErrCatalogUnavailable
+	}
+	coin, ok := s.coins[strings.ToLower(strings.TrimSpace(coinID))]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCoin, coinID)
+	}
+	return &coin, nil
+}
+
+// Validate checks that coinID is in the catalog. Unknown IDs are reported
+// with the closest matches as suggestions.
+func (s *Service) Validate(coinID string) error {
+	_, err := s.Get(coinID)
+	if !errors.Is(err, ErrUnknownCoin) {
+		return err
+	}
+	suggestions := s.Search(coinID, 3)
+	if len(suggestions) == 0 {
+		return err
+	}
+	ids := make([]string, len(suggestions))
+	for i, coin := range suggestions {
+		ids[i] = coin.ID
+	}
+	return fmt.Errorf("%w; did you mean %s?", err, strings.Join(ids, ", "))
+}
+
+// preferredSymbols settles symbols that major coins share with bridged or
+// copycat tokens
+var preferredSymbols = map[string]string{
+	"btc":   "bitcoin",
+	"eth":   "ethereum",
+	"usdt":  "tether",
+	"usdc":  "usd-coin",
+	"dai":   "dai",
+	"bnb":   "binancecoin",
+	"sol":   "solana",
+	"xrp":   "ripple",
+	"ada":   "cardano",
+	"doge":  "dogecoin",
+	"dot":   "polkadot",
+	"ltc":   "litecoin",
+	"link":  "chainlink",
+	"avax":  "avalanche-2",
+	"matic": "matic-network",
+	"trx":   "tron",
+	"shib":  "shiba-inu",
+	"uni":   "uniswap",
+	"atom":  "cosmos",
+	"xlm":   "stellar",
+	"bch":   "bitcoin-cash",
+}
+
+// Resolve maps a catalog coin ID or a ticker symbol to a coin ID. Symbols are
+// often shared by many tokens; a major coin's symbol resolves to that coin,
+// and otherwise a single native coin (listed on no platform) wins. Anything
+// else is ErrAmbiguousSymbol naming the candidates.
+func (s *Service) Resolve(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.coins) == 0 {
+		return "", ErrCatalogUnavailable
+	}
+	ids := s.bySymbol[value]
+	switch {
+	case len(ids) == 1:
+		return ids[0], nil
+	case len(ids) > 1:
+		if preferred, ok := preferredSymbols[value]; ok {
+			if _, listed := s.coins[preferred]; listed {
+				return preferred, nil
+			}
+		}
+		var native []string
+		for _, id := range ids {
+			if len(s.coins[id].Platforms) == 0 {
+				native = append(native, id)
+			}
+		}
+		if len(native) == 1 {
+			return native[0], nil
+		}
+		return "", fmt.Errorf("%w %q: one of %s", ErrAmbiguousSymbol, value, strings.Join(ids, ", "))
+	}
+	if _, ok := s.coins[value]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownCoin, value)
+}
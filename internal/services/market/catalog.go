@@ -0,0 +1,32 @@
+package market
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrCatalogUnsupported = errors.New("no configured provider serves a coin list")
+
+// CoinInfo is one entry of an upstream coin list. Platforms maps chain names
+// to token contract addresses.
+type CoinInfo struct {
+	ID        string            `json:"id"`
+	Symbol    string            `json:"symbol"`
+	Name      string            `json:"name"`
+	Platforms map[string]string `json:"platforms"`
+}
+
+// CatalogProvider is implemented by providers that can list every coin they know
+type CatalogProvider interface {
+	CoinList(ctx context.Context) ([]CoinInfo, error)
+}
+
+// CoinList fetches the full upstream coin list. It is not cached; callers
+// persist it and refresh on their own schedule.
+func (s *Service) CoinList(ctx context.Context) ([]CoinInfo, error) {
+	cp, ok := s.provider.(CatalogProvider)
+	if !ok {
+		return nil, ErrCatalogUnsupported
+	}
+	return cp.CoinList(ctx)
+}
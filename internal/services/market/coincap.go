@@ -0,0 +1,170 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CoinCapProvider adapts CoinCap's /assets API. Most CoinCap asset IDs
+// match CoinGecko's; the ones that differ are translated through
+// coinCapIDs in both directions. CoinCap only quotes USD; other currencies
+// fail so a composite falls through to the next provider.
+type CoinCapProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewCoinCapProvider(baseURL, apiKey string, client *http.Client) *CoinCapProvider {
+	return &CoinCapProvider{baseURL: baseURL, apiKey: apiKey, client: client}
+}
+
+// CoinCap encodes all numbers as strings
+type coinCapAsset struct {
+	ID                string `json:"id"`
+	Symbol            string `json:"symbol"`
+	Name              string `json:"name"`
+	PriceUsd          string `json:"priceUsd"`
+	ChangePercent24Hr string `json:"changePercent24Hr"`
+}
+
+type coinCapAssetsResponse struct {
+	Data []coinCapAsset `json:"data"`
+}
+
+var errCoinCapCurrency = errors.New("coincap only quotes usd")
+
+// coinCapIDs maps CoinGecko coin IDs to CoinCap asset IDs where they differ
+var coinCapIDs = map[string]string{
+	"binancecoin":      "binance-coin",
+	"ripple":           "xrp",
+	"avalanche-2":      "avalanche",
+	"matic-network":    "polygon",
+	"the-open-network": "toncoin",
+	"crypto-com-chain": "crypto-com-coin",
+	"near":             "near-protocol",
+	"elrond-erd-2":     "multiversx",
+	"bitcoin-cash-sv":  "bitcoin-sv",
+	"kucoin-shares":    "kucoin-token",
+}
+
+// coinGeckoIDs is coinCapIDs inverted
+var coinGeckoIDs = func() map[string]string {
+	inverted := make(map[string]string, len(coinCapIDs))
+	for geckoID, capID := range coinCapIDs {
+		inverted[capID] = geckoID
+	}
+	return inverted
+}()
+
+// toCoinCapIDs translates coin IDs into a CoinCap ids parameter
+func toCoinCapIDs(ids []string) string {
+	translated := make([]string, len(ids))
+	for i, id := range ids {
+		if capID, ok := coinCapIDs[id]; ok {
+			id = capID
+		}
+		translated[i] = id
+	}
+	return strings.Join(translated, ",")
+}
+
+// coinGeckoID translates a CoinCap asset ID back to a coin ID
+func coinGeckoID(assetID string) string {
+	if id, ok := coinGeckoIDs[assetID]; ok {
+		return id
+	}
+	return assetID
+}
+
+func (p *CoinCapProvider) Name() string { return "coincap" }
+
+func (p *CoinCapProvider) TopMarkets(ctx context.Context, currency string, limit int) ([]CoinMarket, error) {
+	if currency != "usd" {
+		return nil, errCoinCapCurrency
+	}
+	q := url.Values{}
+	q.Set("limit", fmt.Sprintf("%d", limit))
+
+	var raw coinCapAssetsResponse
+	if err := p.get(ctx, "/assets", q, &raw); err != nil {
+		return nil, err
+	}
+
+	return raw.markets(p.Name()), nil
+}
+
+func (p *CoinCapProvider) Markets(ctx context.Context, ids []string, currency string) ([]CoinMarket, error) {
+	if currency != "usd" {
+		return nil, errCoinCapCurrency
+	}
+	q := url.Values{}
+	q.Set("ids", toCoinCapIDs(ids))
+
+	var raw coinCapAssetsResponse
+	if err := p.get(ctx, "/assets", q, &raw); err != nil {
+		return nil, err
+	}
+	return raw.markets(p.Name()), nil
+}
+
+// markets converts assets to market rows, skipping ones without a price.
+// CoinCap has no sparklines, so the rows leave SparklineIn7D empty and the
+// payload is reported as partial.
+func (r coinCapAssetsResponse) markets(source string) []CoinMarket {
+	payload := make([]CoinMarket, 0, len(r.Data))
+	for _, asset := range r.Data {
+		price, err := strconv.ParseFloat(asset.PriceUsd, 64)
+		if err != nil {
+			continue
+		}
+		change, _ := strconv.ParseFloat(asset.ChangePercent24Hr, 64)
+		payload = append(payload, CoinMarket{
+			ID:                       coinGeckoID(asset.ID),
+			Symbol:                   strings.ToLower(asset.Symbol),
+			Name:                     asset.Name,
+			CurrentPrice:             price,
+			PriceChangePercentage24h: change,
+			Source:                   source,
+		})
+	}
+	return payload
+}
+
+func (p *CoinCapProvider) Prices(ctx context.Context, ids []string, currency string) (map[string]Quote, error) {
+	if currency != "usd" {
+		return nil, errCoinCapCurrency
+	}
+	q := url.Values{}
+	q.Set("ids", toCoinCapIDs(ids))
+
+	var raw coinCapAssetsResponse
+	if err := p.get(ctx, "/assets", q, &raw); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]Quote, len(raw.Data))
+	for _, asset := range raw.Data {
+		if price, err := strconv.ParseFloat(asset.PriceUsd, 64); err == nil {
+			prices[coinGeckoID(asset.ID)] = Quote{Price: price, Source: p.Name()}
+		}
+	}
+	return prices, nil
+}
+
+func (p *CoinCapProvider) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return fetchJSON(p.client, req, p.Name(), out)
+}
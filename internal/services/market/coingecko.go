@@ -0,0 +1,255 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// coinGeckoPriceBatch caps the number of IDs sent in a single /simple/price call
+const coinGeckoPriceBatch = 100
+
+type CoinGeckoProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewCoinGeckoProvider(baseURL, apiKey string, client *http.Client) *CoinGeckoProvider {
+	return &CoinGeckoProvider{baseURL: baseURL, apiKey: apiKey, client: client}
+}
+
+type CoinGeckoMarketResponse struct {
+	ID                       string  `json:"id"`
+	Symbol                   string  `json:"symbol"`
+	Name                     string  `json:"name"`
+	CurrentPrice             float64 `json:"current_price"`
+	PriceChangePercentage24h float64 `json:"price_change_percentage_24h"`
+	SparklineIn7D            struct {
+		Price []float64 `json:"price"`
+	} `json:"sparkline_in_7d"`
+}
+
+func (p *CoinGeckoProvider) Name() string { return "coingecko" }
+
+func (p *CoinGeckoProvider) TopMarkets(ctx context.Context, currency string, limit int) ([]CoinMarket, error) {
+	q := url.Values{}
+	q.Set("vs_currency", currency)
+	q.Set("order", "market_cap_desc")
+	q.Set("per_page", fmt.Sprintf("%d", limit))
+	//q.Set("page", "1")
+	q.Set("sparkline", "true")
+
+	var rawPayload []CoinGeckoMarketResponse
+	if err := p.get(ctx, "/coins/markets", q, &rawPayload); err != nil {
+		return nil, err
+	}
+	return toCoinMarkets(rawPayload, p.Name()), nil
+}
+
+func (p *CoinGeckoProvider) Markets(ctx context.Context, ids []string, currency string) ([]CoinMarket, error) {
+	var payload []CoinMarket
+	for start := 0; start < len(ids); start += coinGeckoPriceBatch {
+		end := start + coinGeckoPriceBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		q := url.Values{}
+		q.Set("vs_currency", currency)
+		q.Set("ids", strings.Join(ids[start:end], ","))
+		q.Set("per_page", fmt.Sprintf("%d", end-start))
+		q.Set("sparkline", "true")
+
+		var rawPayload []CoinGeckoMarketResponse
+		if err := p.get(ctx, "/coins/markets", q, &rawPayload); err != nil {
+			return nil, err
+		}
+		payload = append(payload, toCoinMarkets(rawPayload, p.Name())...)
+	}
+	return payload, nil
+}
+
+// toCoinMarkets transforms CoinGecko rows to our format
+func toCoinMarkets(rawPayload []CoinGeckoMarketResponse, source string) []CoinMarket {
+	payload := make([]CoinMarket, 0, len(rawPayload))
+	for _, coin := range rawPayload {
+		payload = append(payload, CoinMarket{
+			ID:                       coin.ID,
+			Symbol:                   coin.Symbol,
+			Name:                     coin.Name,
+			CurrentPrice:             coin.CurrentPrice,
+			PriceChangePercentage24h: coin.PriceChangePercentage24h,
+			SparklineIn7D: Sparkline{
+				Price: coin.SparklineIn7D.Price,
+			},
+			Source: source,
+		})
+	}
+	return payload
+}
+
+func (p *CoinGeckoProvider) Prices(ctx context.Context, ids []string, currency string) (map[string]Quote, error) {
+	prices := make(map[string]Quote, len(ids))
+	for start := 0; start < len(ids); start += coinGeckoPriceBatch {
+		end := start + coinGeckoPriceBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		q := url.Values{}
+		q.Set("ids", strings.Join(ids[start:end], ","))
+		q.Set("vs_currencies", currency)
+
+		var raw map[string]map[string]float64
+		if err := p.get(ctx, "/simple/price", q, &raw); err != nil {
+			return nil, err
+		}
+		for id, quotes := range raw {
+			if price, ok := quotes[currency]; ok {
+				prices[id] = Quote{Price: price, Source: p.Name()}
+			}
+		}
+	}
+	return prices, nil
+}
+
+func (p *CoinGeckoProvider) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	if p.apiKey != "" {
+		// CORRECT: This is for the free "Demo" plan
+		req.Header.Set("x-cg-demo-api-key", p.apiKey)
+	}
+	return fetchJSON(p.client, req, p.Name(), out)
+}
+
+type coinGeckoMarketChart struct {
+	Prices       [][2]float64 `json:"prices"`
+	MarketCaps   [][2]float64 `json:"market_caps"`
+	TotalVolumes [][2]float64 `json:"total_volumes"`
+}
+
+func (p *CoinGeckoProvider) History(ctx context.Context, coinID, currency, days string, daily bool) ([]PricePoint, error) {
+	q := url.Values{}
+	q.Set("vs_currency", currency)
+	q.Set("days", days)
+	if daily {
+		q.Set("interval", "daily")
+	}
+
+	var raw coinGeckoMarketChart
+	if err := p.get(ctx, "/coins/"+url.PathEscape(coinID)+"/market_chart", q, &raw); err != nil {
+		return nil, coinError(coinID, err)
+	}
+	return raw.points(p.Name()), nil
+}
+
+func (p *CoinGeckoProvider) HistoryRange(ctx context.Context, coinID, currency string, from, to time.Time) ([]PricePoint, error) {
+	q := url.Values{}
+	q.Set("vs_currency", currency)
+	q.Set("from", strconv.FormatInt(from.Unix(), 10))
+	q.Set("to", strconv.FormatInt(to.Unix(), 10))
+
+	var raw coinGeckoMarketChart
+	if err := p.get(ctx, "/coins/"+url.PathEscape(coinID)+"/market_chart/range", q, &raw); err != nil {
+		return nil, coinError(coinID, err)
+	}
+	return raw.points(p.Name()), nil
+}
+
+// coinError reports a 404 from a coin-scoped endpoint as ErrCoinNotFound
+func coinError(coinID string, err error) error {
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return fmt.Errorf("%w: %q", ErrCoinNotFound, coinID)
+	}
+	return err
+}
+
+func (c coinGeckoMarketChart) points(source string) []PricePoint {
+	points := make([]PricePoint, 0, len(c.Prices))
+	for i, price := range c.Prices {
+		point := PricePoint{
+			Timestamp: time.UnixMilli(int64(price[0])).UTC(),
+			Price:     price[1],
+			Source:    source,
+		}
+		// The three series share timestamps, so they can be zipped by index
+		if i < len(c.MarketCaps) {
+			point.MarketCap = c.MarketCaps[i][1]
+		}
+		if i < len(c.TotalVolumes) {
+			point.Volume = c.TotalVolumes[i][1]
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+func (p *CoinGeckoProvider) OHLC(ctx context.Context, coinID, currency, days string) ([]Candle, error) {
+	q := url.Values{}
+	q.Set("vs_currency", currency)
+	q.Set("days", days)
+
+	var raw [][5]float64
+	if err := p.get(ctx, "/coins/"+url.PathEscape(coinID)+"/ohlc", q, &raw); err != nil {
+		return nil, coinError(coinID, err)
+	}
+
+	candles := make([]Candle, 0, len(raw))
+	for _, c := range raw {
+		candles = append(candles, Candle{
+			Timestamp: time.UnixMilli(int64(c[0])).UTC(),
+			Open:      c[1],
+			High:      c[2],
+			Low:       c[3],
+			Close:     c[4],
+		})
+	}
+	return candles, nil
+}
+
+type coinGeckoExchangeRates struct {
+	Rates map[string]struct {
+		Value float64 `json:"value"`
+	} `json:"rates"`
+}
+
+// ExchangeRates returns CoinGecko's BTC-denominated rates for every currency it lists
+func (p *CoinGeckoProvider) ExchangeRates(ctx context.Context) (map[string]float64, error) {
+	var raw coinGeckoExchangeRates
+	if err := p.get(ctx, "/exchange_rates", url.Values{}, &raw); err != nil {
+		return nil, err
+	}
+	rates := make(map[string]float64, len(raw.Rates))
+	for code, rate := range raw.Rates {
+		rates[code] = rate.Value
+	}
+	return rates, nil
+}
+
+func (p *CoinGeckoProvider) CoinList(ctx context.Context) ([]CoinInfo, error) {
+	q := url.Values{}
+	q.Set("include_platform", "true")
+
+	var raw []CoinInfo
+	if err := p.get(ctx, "/coins/list", q, &raw); err != nil {
+		return nil, err
+	}
+	for i := range raw {
+		// Native coins list their own chain with an empty address
+		for chain, address := range raw[i].Platforms {
+			if chain == "" || address == "" {
+				delete(raw[i].Platforms, chain)
+			}
+		}
+	}
+	return raw, nil
+}
@@ -0,0 +1,282 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CompositeProvider tries its providers in priority order. A provider that
+// fails with an outage is skipped for the cooldown period so a rate-limited
+// upstream isn't hammered on every request; if every provider is cooling
+// down they are all tried anyway. A provider that merely does not quote the
+// requested currency is passed over without a cooldown.
+type CompositeProvider struct {
+	providers []PriceProvider
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	downUntil map[string]time.Time
+}
+
+func NewCompositeProvider(cooldown time.Duration, providers ...PriceProvider) *CompositeProvider {
+	return &CompositeProvider{
+		providers: providers,
+		cooldown:  cooldown,
+		downUntil: make(map[string]time.Time),
+	}
+}
+
+func (p *CompositeProvider) Name() string { return "composite" }
+
+func (p *CompositeProvider) TopMarkets(ctx context.Context, currency string, limit int) ([]CoinMarket, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		data, err := provider.TopMarkets(ctx, currency, limit)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return data, nil
+	}
+	return nil, fmt.Errorf("all market providers failed: %w", errors.Join(errs...))
+}
+
+// Prices asks each provider in turn for the IDs still missing, so a coin
+// unknown to the primary can still be priced by a fallback.
+func (p *CompositeProvider) Prices(ctx context.Context, ids []string, currency string) (map[string]Quote, error) {
+	prices := make(map[string]Quote, len(ids))
+	missing := ids
+	var errs []error
+	succeeded := false
+	for _, provider := range p.ordered() {
+		if len(missing) == 0 {
+			break
+		}
+		data, err := provider.Prices(ctx, missing, currency)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		succeeded = true
+
+		var next []string
+		for _, id := range missing {
+			if price, ok := data[id]; ok {
+				prices[id] = price
+			} else {
+				next = append(next, id)
+			}
+		}
+		missing = next
+	}
+	if !succeeded && len(errs) > 0 {
+		return nil, fmt.Errorf("all market providers failed: %w", errors.Join(errs...))
+	}
+	return prices, nil
+}
+
+// Markets, like Prices, asks later providers only for the coins earlier ones
+// did not return
+func (p *CompositeProvider) Markets(ctx context.Context, ids []string, currency string) ([]CoinMarket, error) {
+	var markets []CoinMarket
+	missing := ids
+	var errs []error
+	succeeded := false
+	for _, provider := range p.ordered() {
+		if len(missing) == 0 {
+			break
+		}
+		data, err := provider.Markets(ctx, missing, currency)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		succeeded = true
+
+		found := make(map[string]bool, len(data))
+		for _, coin := range data {
+			found[coin.ID] = true
+		}
+		markets = append(markets, data...)
+		var next []string
+		for _, id := range missing {
+			if !found[id] {
+				next = append(next, id)
+			}
+		}
+		missing = next
+	}
+	if !succeeded && len(errs) > 0 {
+		return nil, fmt.Errorf("all market providers failed: %w", errors.Join(errs...))
+	}
+	return markets, nil
+}
+
+// ordered returns healthy providers first, then cooling-down ones, each
+// group in configured priority order
+func (p *CompositeProvider) ordered() []PriceProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]PriceProvider, 0, len(p.providers))
+	var cooling []PriceProvider
+	for _, provider := range p.providers {
+		if now.Before(p.downUntil[provider.Name()]) {
+			cooling = append(cooling, provider)
+			continue
+		}
+		healthy = append(healthy, provider)
+	}
+	return append(healthy, cooling...)
+}
+
+func (p *CompositeProvider) markDown(provider PriceProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downUntil[provider.Name()] = time.Now().Add(p.cooldown)
+}
+
+func (p *CompositeProvider) markUp(provider PriceProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.downUntil, provider.Name())
+}
+
+// fail puts provider into cooldown when err is an outage rather than an
+// answer about the request
+func (p *CompositeProvider) fail(provider PriceProvider, err error) {
+	if isOutage(err) {
+		p.markDown(provider)
+	}
+}
+
+// allFailed combines the errors of every provider tried. When each one only
+// reported the coin as unknown, that answer is returned as-is.
+func allFailed(what string, errs []error) error {
+	for _, err := range errs {
+		if !errors.Is(err, ErrCoinNotFound) {
+			return fmt.Errorf("all %s providers failed: %w", what, errors.Join(errs...))
+		}
+	}
+	return errs[0]
+}
+
+func (p *CompositeProvider) History(ctx context.Context, coinID, currency, days string, daily bool) ([]PricePoint, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		hp, ok := provider.(HistoryProvider)
+		if !ok {
+			continue
+		}
+		points, err := hp.History(ctx, coinID, currency, days, daily)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return points, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrHistoryUnsupported
+	}
+	return nil, allFailed("history", errs)
+}
+
+func (p *CompositeProvider) OHLC(ctx context.Context, coinID, currency, days string) ([]Candle, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		hp, ok := provider.(HistoryProvider)
+		if !ok {
+			continue
+		}
+		candles, err := hp.OHLC(ctx, coinID, currency, days)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return candles, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrHistoryUnsupported
+	}
+	return nil, allFailed("history", errs)
+}
+
+func (p *CompositeProvider) HistoryRange(ctx context.Context, coinID, currency string, from, to time.Time) ([]PricePoint, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		hp, ok := provider.(HistoryProvider)
+		if !ok {
+			continue
+		}
+		points, err := hp.HistoryRange(ctx, coinID, currency, from, to)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return points, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrHistoryUnsupported
+	}
+	return nil, allFailed("history", errs)
+}
+
+func (p *CompositeProvider) ExchangeRates(ctx context.Context) (map[string]float64, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		rp, ok := provider.(RateProvider)
+		if !ok {
+			continue
+		}
+		rates, err := rp.ExchangeRates(ctx)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return rates, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrRatesUnsupported
+	}
+	return nil, allFailed("rate", errs)
+}
+
+func (p *CompositeProvider) CoinList(ctx context.Context) ([]CoinInfo, error) {
+	var errs []error
+	for _, provider := range p.ordered() {
+		cp, ok := provider.(CatalogProvider)
+		if !ok {
+			continue
+		}
+		coins, err := cp.CoinList(ctx)
+		if err != nil {
+			p.fail(provider, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.markUp(provider)
+		return coins, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrCatalogUnsupported
+	}
+	return nil, allFailed("catalog", errs)
+}
@@ -0,0 +1,217 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidRange       = errors.New("invalid range")
+	ErrInvalidGranularity = errors.New("invalid granularity")
+	ErrHistoryUnsupported = errors.New("no configured provider serves historical data")
+)
+
+type PricePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Price     float64   `json:"price"`
+	MarketCap float64   `json:"marketCap"`
+	Volume    float64   `json:"volume"`
+	// Source names the provider the point came from
+	Source string `json:"-"`
+}
+
+type Candle struct {
+	Timestamp time.Time `json:"timestamp"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+}
+
+// HistoryProvider is implemented by providers that can serve historical
+// series. days is CoinGecko-style: a positive integer or "max".
+type HistoryProvider interface {
+	History(ctx context.Context, coinID, currency, days string, daily bool) ([]PricePoint, error)
+	OHLC(ctx context.Context, coinID, currency, days string) ([]Candle, error)
+	// HistoryRange returns the raw series between two instants
+	HistoryRange(ctx context.Context, coinID, currency string, from, to time.Time) ([]PricePoint, error)
+}
+
+// historyRanges maps the public range parameter to upstream day counts
+var historyRanges = map[string]string{
+	"1d":   "1",
+	"7d":   "7",
+	"14d":  "14",
+	"30d":  "30",
+	"90d":  "90",
+	"180d": "180",
+	"1y":   "365",
+	"max":  "max",
+}
+
+// granularities maps the public granularity parameter to a bucket size.
+// "auto" keeps whatever resolution the upstream returns for the range.
+var granularities = map[string]time.Duration{
+	"auto":   0,
+	"hourly": time.Hour,
+	"4h":     4 * time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+func parseHistoryParams(rng, granularity string) (string, time.Duration, error) {
+	if rng == "" {
+		rng = "30d"
+	}
+	if granularity == "" {
+		granularity = "auto"
+	}
+	days, ok := historyRanges[rng]
+	if !ok {
+		return "", 0, fmt.Errorf("%w %q", ErrInvalidRange, rng)
+	}
+	step, ok := granularities[granularity]
+	if !ok {
+		return "", 0, fmt.Errorf("%w %q", ErrInvalidGranularity, granularity)
+	}
+	return days, step, nil
+}
+
+func (s *Service) historyProvider() (HistoryProvider, error) {
+	hp, ok := s.provider.(HistoryProvider)
+	if !ok {
+		return nil, ErrHistoryUnsupported
+	}
+	return hp, nil
+}
+
+// GetHistory returns the price series for coinID in currency over rng,
+// resampled to granularity by keeping the last point in each bucket
+func (s *Service) GetHistory(coinID, currency, rng, granularity string) ([]PricePoint, error) {
+	days, step, err := parseHistoryParams(rng, granularity)
+	if err != nil {
+		return nil, err
+	}
+	if currency, err = s.NormalizeCurrency(currency); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("history:%s:%s:%s:%s", coinID, currency, days, granularity)
+	if cached, found := s.cache.Get(key); found {
+		return cached.([]PricePoint), nil
+	}
+	hp, err := s.historyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		// Ask for daily points upstream when that's all we need; it is cheaper
+		// and lets long ranges keep daily resolution
+		points, err := hp.History(context.Background(), coinID, currency, days, step >= 24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		points = resamplePoints(points, step)
+		s.cache.Set(key, points, time.Duration(s.cfg.HistoryCacheTTLSeconds)*time.Second)
+		return points, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]PricePoint), nil
+}
+
+// GetOHLC returns candles for coinID in currency over rng, merged into
+// granularity buckets. Buckets finer than the upstream's native resolution
+// are left as-is.
+func (s *Service) GetOHLC(coinID, currency, rng, granularity string) ([]Candle, error) {
+	days, step, err := parseHistoryParams(rng, granularity)
+	if err != nil {
+		return nil, err
+	}
+	if currency, err = s.NormalizeCurrency(currency); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("ohlc:%s:%s:%s:%s", coinID, currency, days, granularity)
+	if cached, found := s.cache.Get(key); found {
+		return cached.([]Candle), nil
+	}
+	hp, err := s.historyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		candles, err := hp.OHLC(context.Background(), coinID, currency, days)
+		if err != nil {
+			return nil, err
+		}
+		candles = mergeCandles(candles, step)
+		s.cache.Set(key, candles, time.Duration(s.cfg.OHLCCacheTTLSeconds)*time.Second)
+		return candles, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Candle), nil
+}
+
+// GetHistoryRange fetches the uncached USD price series between from and to.
+// It is meant for backfilling the local price store, not for serving charts.
+func (s *Service) GetHistoryRange(ctx context.Context, coinID string, from, to time.Time) ([]PricePoint, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidRange)
+	}
+	hp, err := s.historyProvider()
+	if err != nil {
+		return nil, err
+	}
+	return hp.HistoryRange(ctx, coinID, "usd", from, to)
+}
+
+// ProviderName identifies the upstream chain prices come from
+func (s *Service) ProviderName() string {
+	return s.provider.Name()
+}
+
+func resamplePoints(points []PricePoint, step time.Duration) []PricePoint {
+	if step <= 0 || len(points) == 0 {
+		return points
+	}
+	out := make([]PricePoint, 0, len(points))
+	for _, p := range points {
+		bucket := p.Timestamp.Truncate(step)
+		if n := len(out); n > 0 && out[n-1].Timestamp.Truncate(step).Equal(bucket) {
+			out[n-1] = p
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func mergeCandles(candles []Candle, step time.Duration) []Candle {
+	if step <= 0 || len(candles) == 0 {
+		return candles
+	}
+	out := make([]Candle, 0, len(candles))
+	for _, c := range candles {
+		bucket := c.Timestamp.Truncate(step)
+		if n := len(out); n > 0 && out[n-1].Timestamp.Equal(bucket) {
+			last := &out[n-1]
+			if c.High > last.High {
+				last.High = c.High
+			}
+			if c.Low < last.Low {
+				last.Low = c.Low
+			}
+			last.Close = c.Close
+			continue
+		}
+		c.Timestamp = bucket
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package market
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// PollerStatus is a point-in-time view of the background refresher
+type PollerStatus struct {
+	Running             bool       `json:"running"`
+	IntervalSeconds     int        `json:"intervalSeconds"`
+	LastRun             *time.Time `json:"lastRun"`
+	LastSuccess         *time.Time `json:"lastSuccess"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	NextRun             *time.Time `json:"nextRun"`
+}
+
+// poller refreshes market data on a fixed interval. After a failed run the
+// next attempt waits minBackoff doubled on each consecutive failure, never
+// less than interval and capped at maxBackoff, so a failing or rate-limiting
+// upstream is polled less often than a healthy one.
+type poller struct {
+	interval   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	refresh    func(ctx context.Context) error
+
+	mu     sync.Mutex
+	status PollerStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (p *poller) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil || p.interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	p.status.Running = true
+	go p.loop(ctx)
+}
+
+// stop cancels the loop and waits for an in-flight refresh to finish or ctx to expire
+func (p *poller) stop(ctx context.Context) error {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel = nil
+	p.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *poller) loop(ctx context.Context) {
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.status.NextRun = nil
+		p.mu.Unlock()
+		close(p.done)
+	}()
+
+	delay := time.Duration(0)
+	for {
+		next := time.Now().Add(delay)
+		p.mu.Lock()
+		p.status.NextRun = &next
+		p.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		delay = p.run(ctx)
+	}
+}
+
+// run performs one refresh, records the outcome and returns the delay until the next run
+func (p *poller) run(ctx context.Context) time.Duration {
+	err := p.refresh(ctx)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.LastRun = &now
+	if err == nil {
+		p.status.LastSuccess = &now
+		p.status.ConsecutiveFailures = 0
+		return p.interval
+	}
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	p.status.LastError = err.Error()
+	p.status.LastErrorAt = &now
+	p.status.ConsecutiveFailures++
+	log.Printf("market: poll failed (%d in a row): %v", p.status.ConsecutiveFailures, err)
+
+	backoff := p.minBackoff
+	for i := 1; i < p.status.ConsecutiveFailures && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(max(backoff, p.interval), p.maxBackoff)
+}
+
+func (p *poller) snapshot() PollerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	status.IntervalSeconds = int(p.interval.Seconds())
+	return status
+}
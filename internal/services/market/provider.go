@@ -0,0 +1,109 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/config"
+)
+
+// PriceProvider is an upstream source of market data. Coin IDs follow
+// CoinGecko's naming (e.g. "bitcoin") and currencies are lower-case codes
+// (e.g. "usd"); adapters translate as needed.
+type PriceProvider interface {
+	Name() string
+	// TopMarkets returns the top coins by market cap priced in currency
+	TopMarkets(ctx context.Context, currency string, limit int) ([]CoinMarket, error)
+	// Prices returns quotes in currency keyed by coin ID; unknown IDs are omitted
+	Prices(ctx context.Context, ids []string, currency string) (map[string]Quote, error)
+	// Markets returns market rows for specific coins, including ones outside
+	// the top N; unknown IDs are omitted
+	Markets(ctx context.Context, ids []string, currency string) ([]CoinMarket, error)
+}
+
+// Quote is a coin's price together with the provider that supplied it
+type Quote struct {
+	Price  float64
+	Source string
+}
+
+// RateProvider is implemented by providers that publish fiat exchange rates.
+// Rates are expressed as units of each currency per one common reference unit.
+type RateProvider interface {
+	ExchangeRates(ctx context.Context) (map[string]float64, error)
+}
+
+// NewProvider builds the provider chain listed in cfg.MarketProviders.
+// A single entry is returned as-is; several are wrapped in a CompositeProvider.
+func NewProvider(cfg *config.Config, client *http.Client) PriceProvider {
+	var providers []PriceProvider
+	for _, name := range cfg.MarketProviders {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "coingecko":
+			providers = append(providers, NewCoinGeckoProvider(cfg.CoinGeckoBaseURL, cfg.CoinGeckoAPIKey, client))
+		case "coincap":
+			providers = append(providers, NewCoinCapProvider(cfg.CoinCapBaseURL, cfg.CoinCapAPIKey, client))
+		case "":
+		default:
+			log.Printf("market: ignoring unknown provider %q", name)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return NewCoinGeckoProvider(cfg.CoinGeckoBaseURL, cfg.CoinGeckoAPIKey, client)
+	case 1:
+		return providers[0]
+	}
+	return NewCompositeProvider(time.Duration(cfg.ProviderCooldownSeconds)*time.Second, providers...)
+}
+
+// ErrCoinNotFound is returned when an upstream does not know the requested coin
+var ErrCoinNotFound = errors.New("coin not found")
+
+// statusError is a non-200 upstream response
+type statusError struct {
+	provider string
+	code     int
+	body     string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.provider, e.code, e.body)
+}
+
+// fetchJSON performs req and decodes a 200 JSON response into out. Other
+// statuses are returned as *statusError.
+func fetchJSON(client *http.Client, req *http.Request, provider string, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Read error body for debugging
+		bodyBytes := make([]byte, 512)
+		n, _ := resp.Body.Read(bodyBytes)
+		return &statusError{provider: provider, code: resp.StatusCode, body: string(bodyBytes[:n])}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// isOutage reports whether err means the upstream itself is unhealthy: a
+// transport failure, an unreadable body, a 5xx or a 429. Answers about the
+// request, such as an unknown coin or an unquoted currency, are not outages.
+func isOutage(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= http.StatusInternalServerError
+	}
+	return !errors.Is(err, ErrCoinNotFound) && !errors.Is(err, errCoinCapCurrency) && !errors.Is(err, context.Canceled)
+}
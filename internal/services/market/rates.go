@@ -0,0 +1,55 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/patrickmn/go-cache"
+)
+
+var ErrRatesUnsupported = errors.New("no configured provider serves exchange rates")
+
+// ConversionRate returns how many units of to one unit of from is worth,
+// using current rates. It is used to restate stored amounts (cost basis,
+// historical USD prices) in a user's chosen currency.
+func (s *Service) ConversionRate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	rates, err := s.exchangeRates()
+	if err != nil {
+		return 0, err
+	}
+	fromRate, ok := rates[from]
+	if !ok || fromRate == 0 {
+		return 0, fmt.Errorf("%w %q", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnsupportedCurrency, to)
+	}
+	return toRate / fromRate, nil
+}
+
+func (s *Service) exchangeRates() (map[string]float64, error) {
+	if cached, found := s.cache.Get("rates"); found {
+		return cached.(map[string]float64), nil
+	}
+	rp, ok := s.provider.(RateProvider)
+	if !ok {
+		return nil, ErrRatesUnsupported
+	}
+	v, err, _ := s.group.Do("rates", func() (interface{}, error) {
+		rates, err := rp.ExchangeRates(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set("rates", rates, cache.DefaultExpiration)
+		return rates, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]float64), nil
+}
@@ -0,0 +1,68 @@
+package market
+
+import (
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/services/risk"
+)
+
+// RiskBenchmark is the coin betas are measured against
+const RiskBenchmark = "bitcoin"
+
+// RiskPeriodsPerYear annualizes daily returns; crypto trades every day
+const RiskPeriodsPerYear = 365
+
+// DailySeries returns daily prices for coinID over rng as risk points
+func (s *Service) DailySeries(coinID, currency, rng string) ([]risk.Point, error) {
+	history, err := s.GetHistory(coinID, currency, rng, "daily")
+	if err != nil {
+		return nil, err
+	}
+	points := make([]risk.Point, len(history))
+	for i, p := range history {
+		points[i] = risk.Point{Time: p.Timestamp, Value: p.Price}
+	}
+	return points, nil
+}
+
+// RiskFreeRate is the configured annual risk-free rate as a fraction
+func (s *Service) RiskFreeRate() float64 {
+	return s.cfg.RiskFreeRatePercent / 100
+}
+
+type CoinRisk struct {
+	CoinID       string  `json:"coinId"`
+	Currency     string  `json:"currency"`
+	Range        string  `json:"range"`
+	Benchmark    string  `json:"benchmark"`
+	RiskFreeRate float64 `json:"riskFreeRate"`
+	risk.Metrics
+}
+
+// GetCoinRisk computes risk metrics from daily prices over rng, with beta
+// measured against RiskBenchmark
+func (s *Service) GetCoinRisk(coinID, currency, rng string) (*CoinRisk, error) {
+	if rng == "" {
+		rng = "90d"
+	}
+	series := make(map[string][]risk.Point, 2)
+	var err error
+	if series[coinID], err = s.DailySeries(coinID, currency, rng); err != nil {
+		return nil, err
+	}
+	if coinID != RiskBenchmark {
+		if series[RiskBenchmark], err = s.DailySeries(RiskBenchmark, currency, rng); err != nil {
+			return nil, err
+		}
+	}
+	times, values := risk.Align(series, 24*time.Hour)
+	result := &CoinRisk{
+		CoinID:       coinID,
+		Currency:     currency,
+		Range:        rng,
+		Benchmark:    RiskBenchmark,
+		RiskFreeRate: s.RiskFreeRate(),
+	}
+	result.Metrics = risk.Compute(times, values[coinID], values[RiskBenchmark], result.RiskFreeRate, RiskPeriodsPerYear)
+	return result, nil
+}
@@ -1,27 +1,90 @@
 package market
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/faisal/crypto/backend/internal/config"
 )
 
 type Service struct {
-	cfg    *config.Config
-	client *http.Client
-	cache  *cache.Cache
+	cfg      *config.Config
+	provider PriceProvider
+	cache    *cache.Cache
+
+	group        singleflight.Group
+	refreshingMu sync.Mutex
+	refreshing   map[string]bool // key: currency
+
+	poller      *poller
+	listenersMu sync.RWMutex
+	listeners   []func(*MarketData)
 }
 
 func NewService(cfg *config.Config) *Service {
-	return &Service{
-		cfg:    cfg,
-		client: &http.Client{Timeout: 10 * time.Second},
-		cache:  cache.New(time.Duration(cfg.CacheTTLSeconds)*time.Second, time.Minute),
+	client := &http.Client{Timeout: 10 * time.Second}
+	s := &Service{
+		cfg:      cfg,
+		provider: NewProvider(cfg, client),
+		cache:    cache.New(time.Duration(cfg.CacheTTLSeconds)*time.Second, time.Minute),
+
+		refreshing: make(map[string]bool),
+	}
+	s.poller = &poller{
+		interval:   time.Duration(cfg.MarketPollIntervalSeconds) * time.Second,
+		minBackoff: 5 * time.Second,
+		maxBackoff: time.Duration(cfg.MarketPollMaxBackoffSeconds) * time.Second,
+		refresh: func(ctx context.Context) error {
+			var errs []error
+			for _, currency := range cfg.MarketPollCurrencies {
+				if _, err := s.refreshMarket(ctx, currency); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", currency, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+	return s
+}
+
+// Start launches the background poller. It is a no-op when polling is
+// disabled (MARKET_POLL_INTERVAL_SECONDS=0) or already running.
+func (s *Service) Start() {
+	s.poller.start()
+}
+
+// Stop halts the background poller, waiting for an in-flight refresh
+func (s *Service) Stop(ctx context.Context) error {
+	return s.poller.stop(ctx)
+}
+
+func (s *Service) PollerStatus() PollerStatus {
+	return s.poller.snapshot()
+}
+
+// OnUpdate registers fn to be called after every successful upstream refresh,
+// whether triggered by the poller or by a request. Listeners run synchronously
+// on the refreshing goroutine and must not block.
+func (s *Service) OnUpdate(fn func(*MarketData)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+func (s *Service) publish(data *MarketData) {
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+	for _, fn := range s.listeners {
+		fn(data)
 	}
 }
 
@@ -32,78 +95,289 @@ type CoinMarket struct {
 	CurrentPrice             float64   `json:"current_price"`
 	PriceChangePercentage24h float64   `json:"price_change_percentage_24h"`
 	SparklineIn7D            Sparkline `json:"sparkline_in_7d"`
+	// Source names the provider the row came from
+	Source string `json:"-"`
 }
 
 type Sparkline struct {
 	Price []float64 `json:"price"`
 }
 
-type CoinGeckoMarketResponse struct {
-	ID                       string  `json:"id"`
-	Symbol                   string  `json:"symbol"`
-	Name                     string  `json:"name"`
-	CurrentPrice             float64 `json:"current_price"`
-	PriceChangePercentage24h float64 `json:"price_change_percentage_24h"`
-	SparklineIn7D            struct {
-		Price []float64 `json:"price"`
-	} `json:"sparkline_in_7d"`
+// MarketData is a top-N payload together with when it was fetched upstream
+type MarketData struct {
+	Coins     []CoinMarket
+	Currency  string
+	FetchedAt time.Time
+	Stale     bool
+	// Partial is set when some rows lack a sparkline, as rows from the
+	// CoinCap failover do
+	Partial bool
+}
+
+// Age reports how long ago the payload was fetched
+func (d *MarketData) Age() time.Duration {
+	return time.Since(d.FetchedAt)
+}
+
+// partialMarkets reports whether any row is missing its 7-day sparkline
+func partialMarkets(coins []CoinMarket) bool {
+	for _, coin := range coins {
+		if len(coin.SparklineIn7D.Price) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// marketEntry is kept in the cache without expiry so the last good payload
+// survives upstream outages; freshness is judged from FetchedAt.
+type marketEntry struct {
+	coins     []CoinMarket
+	fetchedAt time.Time
+}
+
+func (e marketEntry) data(currency string) *MarketData {
+	return &MarketData{Coins: e.coins, Currency: currency, FetchedAt: e.fetchedAt, Partial: partialMarkets(e.coins)}
+}
+
+// refreshRetries and refreshBackoff bound the background revalidation loop
+const (
+	refreshRetries = 3
+	refreshBackoff = 2 * time.Second
+)
+
+// ErrUnsupportedCurrency is returned for vs currencies outside cfg.SupportedCurrencies
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// NormalizeCurrency lower-cases currency, substitutes the configured default
+// when it is empty and rejects currencies that are not supported
+func (s *Service) NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToLower(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = s.cfg.DefaultCurrency
+	}
+	for _, supported := range s.cfg.SupportedCurrencies {
+		if currency == supported {
+			return currency, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnsupportedCurrency, currency)
+}
+
+func (s *Service) GetTopMarketData(currency string) ([]CoinMarket, error) {
+	data, err := s.GetMarketData(currency)
+	if err != nil {
+		return nil, err
+	}
+	return data.Coins, nil
+}
+
+// GetMarketData serves the cached top-N payload for currency. A fresh entry
+// is returned as-is; an expired one is returned marked stale while a single
+// background refresh runs. Only a cold cache blocks on the upstream, and
+// concurrent cold callers share one fetch.
+func (s *Service) GetMarketData(currency string) (*MarketData, error) {
+	currency, err := s.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := s.marketEntry(currency); ok {
+		if time.Since(entry.fetchedAt) < s.ttl() {
+			return entry.data(currency), nil
+		}
+		s.revalidate(currency)
+		data := entry.data(currency)
+		data.Stale = true
+		return data, nil
+	}
+
+	entry, err := s.refreshMarket(context.Background(), currency)
+	if err != nil {
+		return nil, err
+	}
+	return entry.data(currency), nil
+}
+
+func (s *Service) ttl() time.Duration {
+	return time.Duration(s.cfg.CacheTTLSeconds) * time.Second
 }
 
-func (s *Service) GetTopMarketData() ([]CoinMarket, error) {
+func (s *Service) marketEntry(currency string) (marketEntry, bool) {
+	cached, found := s.cache.Get("market:" + currency)
+	if !found {
+		return marketEntry{}, false
+	}
+	return cached.(marketEntry), true
+}
+
+// refreshMarket fetches the top-N list upstream, collapsing concurrent calls
+func (s *Service) refreshMarket(ctx context.Context, currency string) (marketEntry, error) {
+	key := "market:" + currency
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		payload, err := s.provider.TopMarkets(ctx, currency, s.cfg.MarketDataLimit)
+		if err != nil {
+			return nil, err
+		}
+		entry := marketEntry{coins: payload, fetchedAt: time.Now()}
+		s.cache.Set(key, entry, cache.NoExpiration)
+		s.publish(entry.data(currency))
+		return entry, nil
+	})
+	if err != nil {
+		return marketEntry{}, err
+	}
+	return v.(marketEntry), nil
+}
 
-	if cached, found := s.cache.Get("market"); found {
-		return cached.([]CoinMarket), nil
+// revalidate starts a background refresh for currency unless one is already running
+func (s *Service) revalidate(currency string) {
+	s.refreshingMu.Lock()
+	if s.refreshing[currency] {
+		s.refreshingMu.Unlock()
+		return
 	}
+	s.refreshing[currency] = true
+	s.refreshingMu.Unlock()
 
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/coins/markets", s.cfg.CoinGeckoBaseURL), nil)
+	go func() {
+		defer func() {
+			s.refreshingMu.Lock()
+			delete(s.refreshing, currency)
+			s.refreshingMu.Unlock()
+		}()
+		backoff := refreshBackoff
+		for attempt := 1; attempt <= refreshRetries; attempt++ {
+			if _, err := s.refreshMarket(context.Background(), currency); err == nil {
+				return
+			} else if attempt == refreshRetries {
+				log.Printf("market: background refresh failed after %d attempts: %v", attempt, err)
+				return
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}()
+}
+
+// GetPrices returns the price in currency for each requested coin ID. IDs no
+// provider knows are absent from the result rather than reported as errors.
+func (s *Service) GetPrices(ids []string, currency string) (map[string]float64, error) {
+	quotes, err := s.GetQuotes(ids, currency)
 	if err != nil {
 		return nil, err
 	}
-	q := req.URL.Query()
-	q.Set("vs_currency", "usd")
-	q.Set("order", "market_cap_desc")
-	q.Set("per_page", fmt.Sprintf("%d", s.cfg.MarketDataLimit))
-	//q.Set("page", "1")
-	q.Set("sparkline", "true")
-	req.URL.RawQuery = q.Encode()
-	if s.cfg.CoinGeckoAPIKey != "" {
-		// CORRECT: This is for the free "Demo" plan
-		req.Header.Set("x-cg-demo-api-key", s.cfg.CoinGeckoAPIKey)
+	prices := make(map[string]float64, len(quotes))
+	for id, quote := range quotes {
+		prices[id] = quote.Price
 	}
+	return prices, nil
+}
 
-	resp, err := s.client.Do(req)
+// GetQuotes is GetPrices with the provider each price came from
+func (s *Service) GetQuotes(ids []string, currency string) (map[string]Quote, error) {
+	currency, err := s.NormalizeCurrency(currency)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	quotes := make(map[string]Quote, len(ids))
+
+	// Coins already in the top-N payload don't need another round trip
+	if entry, ok := s.marketEntry(currency); ok && time.Since(entry.fetchedAt) < s.ttl() {
+		for _, coin := range entry.coins {
+			quotes[coin.ID] = Quote{Price: coin.CurrentPrice, Source: coin.Source}
+		}
+	}
+
+	var missing []string
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		if _, ok := quotes[id]; ok {
+			continue
+		}
+		if cached, found := s.cache.Get(priceKey(currency, id)); found {
+			quotes[id] = cached.(Quote)
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		fetched, err := s.provider.Prices(context.Background(), missing, currency)
+		if err != nil {
+			return nil, err
+		}
+		for id, quote := range fetched {
+			quotes[id] = quote
+			s.cache.Set(priceKey(currency, id), quote, cache.DefaultExpiration)
+		}
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		// Read error body for debugging
-		bodyBytes := make([]byte, 512)
-		n, _ := resp.Body.Read(bodyBytes)
-		return nil, fmt.Errorf("coingecko returned status %d: %s", resp.StatusCode, string(bodyBytes[:n]))
+	result := make(map[string]Quote, len(seen))
+	for id := range seen {
+		if quote, ok := quotes[id]; ok {
+			result[id] = quote
+		}
 	}
+	return result, nil
+}
 
-	var rawPayload []CoinGeckoMarketResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rawPayload); err != nil {
+// GetCoinMarkets returns market rows for the requested coins keyed by ID,
+// serving top-N coins from the cached payload and fetching the rest. IDs no
+// provider knows are absent from the result.
+func (s *Service) GetCoinMarkets(ids []string, currency string) (map[string]CoinMarket, error) {
+	currency, err := s.NormalizeCurrency(currency)
+	if err != nil {
 		return nil, err
 	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	markets := make(map[string]CoinMarket, len(ids))
+	if entry, ok := s.marketEntry(currency); ok && time.Since(entry.fetchedAt) < s.ttl() {
+		for _, coin := range entry.coins {
+			if wanted[coin.ID] {
+				markets[coin.ID] = coin
+			}
+		}
+	}
 
-	// Transform to our format
-	payload := make([]CoinMarket, 0, len(rawPayload))
-	for _, coin := range rawPayload {
-		payload = append(payload, CoinMarket{
-			ID:                       coin.ID,
-			Symbol:                   coin.Symbol,
-			Name:                     coin.Name,
-			CurrentPrice:             coin.CurrentPrice,
-			PriceChangePercentage24h: coin.PriceChangePercentage24h,
-			SparklineIn7D: Sparkline{
-				Price: coin.SparklineIn7D.Price,
-			},
-		})
+	var missing []string
+	for id := range wanted {
+		if _, ok := markets[id]; ok {
+			continue
+		}
+		if cached, found := s.cache.Get(coinMarketKey(currency, id)); found {
+			markets[id] = cached.(CoinMarket)
+			continue
+		}
+		missing = append(missing, id)
 	}
 
-	s.cache.Set("market", payload, cache.DefaultExpiration)
-	return payload, nil
+	if len(missing) > 0 {
+		fetched, err := s.provider.Markets(context.Background(), missing, currency)
+		if err != nil {
+			return nil, err
+		}
+		for _, coin := range fetched {
+			if !wanted[coin.ID] {
+				continue
+			}
+			markets[coin.ID] = coin
+			s.cache.Set(coinMarketKey(currency, coin.ID), coin, cache.DefaultExpiration)
+		}
+	}
+	return markets, nil
+}
+
+func coinMarketKey(currency, id string) string {
+	return "coinmarket:" + currency + ":" + id
+}
+
+func priceKey(currency, id string) string {
+	return "price:" + currency + ":" + id
 }
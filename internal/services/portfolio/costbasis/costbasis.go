@@ -0,0 +1,297 @@
+// Package costbasis matches disposals against acquisition lots to compute
+// realized gains and the remaining open lots per coin.
+package costbasis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+type Method string
+
+const (
+	FIFO    Method = models.CostBasisFIFO
+	LIFO    Method = models.CostBasisLIFO
+	HIFO    Method = models.CostBasisHIFO
+	Average Method = models.CostBasisAverage
+)
+
+// ErrOversold is returned when a disposal exceeds the coin's open lots
+var ErrOversold = errors.New("disposal exceeds open lots")
+
+// relativeEpsilon absorbs float noise when a lot or position is fully
+// consumed. It is relative because the noise grows with the quantities
+// summed, which run to 1e8 units and more for some tokens.
+const relativeEpsilon = 1e-12
+
+// Negligible reports whether qty is float noise next to scale, the size of
+// the lot or position it was computed from
+func Negligible(qty, scale float64) bool {
+	return math.Abs(qty) <= relativeEpsilon*math.Abs(scale)
+}
+
+func (m Method) Valid() bool {
+	return models.ValidCostBasisMethod(string(m))
+}
+
+// Lot is an open acquisition with the quantity still held. UnknownBasis
+// marks coin transferred in without a matching transfer out, whose cost
+// and acquisition date fall back to the transfer's.
+type Lot struct {
+	TransactionID string    `json:"transactionId"`
+	CoinID        string    `json:"coinId"`
+	Acquired      time.Time `json:"acquired"`
+	Quantity      float64   `json:"quantity"`
+	UnitCost      float64   `json:"unitCost"`
+	UnknownBasis  bool      `json:"unknownBasis,omitempty"`
+}
+
+// Match is the part of a lot consumed by a single disposal
+type Match struct {
+	LotTransactionID string    `json:"lotTransactionId"`
+	Acquired         time.Time `json:"acquired"`
+	Quantity         float64   `json:"quantity"`
+	UnitCost         float64   `json:"unitCost"`
+	UnknownBasis     bool      `json:"unknownBasis,omitempty"`
+}
+
+// Disposal is a sell matched against one or more lots
+type Disposal struct {
+	TransactionID string    `json:"transactionId"`
+	CoinID        string    `json:"coinId"`
+	Disposed      time.Time `json:"disposed"`
+	Quantity      float64   `json:"quantity"`
+	Proceeds      float64   `json:"proceeds"`
+	CostBasis     float64   `json:"costBasis"`
+	Gain          float64   `json:"gain"`
+	Matches       []Match   `json:"matches"`
+}
+
+type Position struct {
+	CoinID       string  `json:"coinId"`
+	Quantity     float64 `json:"quantity"`
+	CostBasis    float64 `json:"costBasis"`
+	RealizedGain float64 `json:"realizedGain"`
+	Lots         []Lot   `json:"lots"`
+}
+
+type Result struct {
+	Method    Method               `json:"method"`
+	Positions map[string]*Position `json:"positions"`
+	Disposals []Disposal           `json:"disposals"`
+}
+
+// Compute replays the ledger in time order. Buys and income open lots at
+// their unit price; sells are disposals that realize a gain; fees remove lots
+// without realizing anything. A transfer out moves lots in transit and the
+// next transfers in of the coin reopen them with their original acquisition
+// date and cost, so moving coin between wallets neither restarts the holding
+// period nor resets the basis. Coin received beyond what is in transit opens
+// an UnknownBasis lot.
+func Compute(transactions []models.Transaction, method Method) (*Result, error) {
+	if !method.Valid() {
+		return nil, fmt.Errorf("unknown cost basis method %q", method)
+	}
+
+	ordered := make([]models.Transaction, len(transactions))
+	copy(ordered, transactions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp < ordered[j].Timestamp
+	})
+
+	res := &Result{Method: method, Positions: make(map[string]*Position)}
+	transit := make(map[string][]Lot)
+	for _, tx := range ordered {
+		pos, ok := res.Positions[tx.CoinID]
+		if !ok {
+			pos = &Position{CoinID: tx.CoinID}
+			res.Positions[tx.CoinID] = pos
+		}
+
+		switch tx.Type {
+		case models.TransactionTransferIn:
+			var lots []Lot
+			lots, transit[tx.CoinID] = receive(tx, transit[tx.CoinID])
+			pos.Lots = append(pos.Lots, lots...)
+		case models.TransactionBuy, models.TransactionIncome:
+			pos.Lots = append(pos.Lots, Lot{
+				TransactionID: tx.ID.Hex(),
+				CoinID:        tx.CoinID,
+				Acquired:      tx.Timestamp.Time().UTC(),
+				Quantity:      tx.Quantity,
+				UnitCost:      tx.UnitPrice,
+			})
+		case models.TransactionSell, models.TransactionTransferOut, models.TransactionFee:
+			matches, err := consume(pos, tx, method)
+			if err != nil {
+				return nil, err
+			}
+			if tx.Type == models.TransactionTransferOut {
+				for _, m := range matches {
+					transit[tx.CoinID] = append(transit[tx.CoinID], Lot{
+						TransactionID: m.LotTransactionID,
+						CoinID:        tx.CoinID,
+						Acquired:      m.Acquired,
+						Quantity:      m.Quantity,
+						UnitCost:      m.UnitCost,
+						UnknownBasis:  m.UnknownBasis,
+					})
+				}
+			}
+			if tx.Type != models.TransactionSell {
+				continue
+			}
+			d := Disposal{
+				TransactionID: tx.ID.Hex(),
+				CoinID:        tx.CoinID,
+				Disposed:      tx.Timestamp.Time().UTC(),
+				Quantity:      tx.Quantity,
+				Proceeds:      tx.Quantity * tx.UnitPrice,
+				Matches:       matches,
+			}
+			for _, m := range matches {
+				d.CostBasis += m.Quantity * m.UnitCost
+			}
+			d.Gain = d.Proceeds - d.CostBasis
+			pos.RealizedGain += d.Gain
+			res.Disposals = append(res.Disposals, d)
+		}
+	}
+
+	for _, pos := range res.Positions {
+		pos.Quantity, pos.CostBasis = 0, 0
+		for _, lot := range pos.Lots {
+			pos.Quantity += lot.Quantity
+			pos.CostBasis += lot.Quantity * lot.UnitCost
+		}
+		if method == Average && pos.Quantity > 0 {
+			pool(pos.Lots)
+		}
+	}
+	return res, nil
+}
+
+// consume removes tx.Quantity from the position's lots in the order dictated
+// by method and drops lots that are fully used.
+func consume(pos *Position, tx models.Transaction, method Method) ([]Match, error) {
+	// Average cost reprices every open lot at the pooled unit cost but still
+	// depletes lots oldest first so acquisition dates stay meaningful for
+	// holding periods.
+	if method == Average {
+		pool(pos.Lots)
+	}
+
+	order := make([]int, len(pos.Lots))
+	for i := range order {
+		order[i] = i
+	}
+	switch method {
+	case LIFO:
+		sort.SliceStable(order, func(a, b int) bool {
+			return pos.Lots[order[a]].Acquired.After(pos.Lots[order[b]].Acquired)
+		})
+	case HIFO:
+		sort.SliceStable(order, func(a, b int) bool {
+			return pos.Lots[order[a]].UnitCost > pos.Lots[order[b]].UnitCost
+		})
+	}
+
+	remaining := tx.Quantity
+	var matches []Match
+	for _, i := range order {
+		if remaining <= 0 {
+			break
+		}
+		lot := &pos.Lots[i]
+		take := min(lot.Quantity, remaining)
+		matches = append(matches, Match{
+			LotTransactionID: lot.TransactionID,
+			Acquired:         lot.Acquired,
+			Quantity:         take,
+			UnitCost:         lot.UnitCost,
+			UnknownBasis:     lot.UnknownBasis,
+		})
+		// Noise in the disposal's quantity lands on the last lot it touches
+		scale := max(lot.Quantity, tx.Quantity)
+		lot.Quantity -= take
+		if Negligible(lot.Quantity, scale) {
+			lot.Quantity = 0
+		}
+		remaining -= take
+		if Negligible(remaining, tx.Quantity) {
+			remaining = 0
+		}
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("%w: %s disposal of %g on %s", ErrOversold, tx.CoinID, tx.Quantity,
+			tx.Timestamp.Time().UTC().Format(time.DateOnly))
+	}
+
+	open := pos.Lots[:0]
+	for _, lot := range pos.Lots {
+		if lot.Quantity > 0 {
+			open = append(open, lot)
+		}
+	}
+	pos.Lots = open
+	return matches, nil
+}
+
+// pool reprices lots at their average unit cost. A single lot of unknown
+// basis leaves the whole pool unknown.
+func pool(lots []Lot) {
+	var qty, cost float64
+	unknown := false
+	for _, lot := range lots {
+		qty += lot.Quantity
+		cost += lot.Quantity * lot.UnitCost
+		unknown = unknown || lot.UnknownBasis
+	}
+	if qty <= 0 {
+		return
+	}
+	for i := range lots {
+		lots[i].UnitCost = cost / qty
+		lots[i].UnknownBasis = unknown
+	}
+}
+
+// receive reopens lots in transit, oldest transfer first, for a transfer in
+// and returns them with what is still in transit. Any excess opens a lot at
+// the transfer's date and unit price marked UnknownBasis.
+func receive(tx models.Transaction, transit []Lot) ([]Lot, []Lot) {
+	var lots []Lot
+	remaining := tx.Quantity
+	for len(transit) > 0 && remaining > 0 {
+		lot := transit[0]
+		take := min(lot.Quantity, remaining)
+		moved := lot
+		moved.Quantity = take
+		lots = append(lots, moved)
+
+		transit[0].Quantity -= take
+		if Negligible(transit[0].Quantity, max(lot.Quantity, tx.Quantity)) {
+			transit = transit[1:]
+		}
+		remaining -= take
+		if Negligible(remaining, tx.Quantity) {
+			remaining = 0
+		}
+	}
+	if remaining > 0 {
+		lots = append(lots, Lot{
+			TransactionID: tx.ID.Hex(),
+			CoinID:        tx.CoinID,
+			Acquired:      tx.Timestamp.Time().UTC(),
+			Quantity:      remaining,
+			UnitCost:      tx.UnitPrice,
+			UnknownBasis:  true,
+		})
+	}
+	return lots, transit
+}
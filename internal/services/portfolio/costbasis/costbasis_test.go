@@ -0,0 +1,215 @@
+package costbasis
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// ledger builds transactions a day apart, one per entry, with IDs that tests
+// can refer back to by index
+type ledger struct {
+	ids []primitive.ObjectID
+	txs []models.Transaction
+}
+
+func (l *ledger) add(kind models.TransactionType, quantity, unitPrice float64) *ledger {
+	id := primitive.NewObjectID()
+	l.ids = append(l.ids, id)
+	l.txs = append(l.txs, models.Transaction{
+		ID:        id,
+		CoinID:    "bitcoin",
+		Type:      kind,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Timestamp: models.ToPrimitiveDateTime(time.Date(2024, 1, 1+len(l.txs), 0, 0, 0, 0, time.UTC)),
+	})
+	return l
+}
+
+func (l *ledger) buy(quantity, unitPrice float64) *ledger {
+	return l.add(models.TransactionBuy, quantity, unitPrice)
+}
+
+func (l *ledger) sell(quantity, unitPrice float64) *ledger {
+	return l.add(models.TransactionSell, quantity, unitPrice)
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestComputeMethods(t *testing.T) {
+	// Lots of 1 at 100, 300 and 200, then a sale of 1.5 at 400
+	l := (&ledger{}).buy(1, 100).buy(1, 300).buy(1, 200).sell(1.5, 400)
+
+	type match struct {
+		lot      int
+		quantity float64
+		unitCost float64
+	}
+	tests := []struct {
+		method      Method
+		wantMatches []match
+		wantGain    float64
+		wantOpen    []int
+		wantBasis   float64
+	}{
+		{
+			method:      FIFO,
+			wantMatches: []match{{0, 1, 100}, {1, 0.5, 300}},
+			wantGain:    350,
+			wantOpen:    []int{1, 2},
+			wantBasis:   350,
+		},
+		{
+			method:      LIFO,
+			wantMatches: []match{{2, 1, 200}, {1, 0.5, 300}},
+			wantGain:    250,
+			wantOpen:    []int{0, 1},
+			wantBasis:   250,
+		},
+		{
+			method:      HIFO,
+			wantMatches: []match{{1, 1, 300}, {2, 0.5, 200}},
+			wantGain:    200,
+			wantOpen:    []int{0, 2},
+			wantBasis:   200,
+		},
+		{
+			// Pooled at 200 a unit, depleted oldest first
+			method:      Average,
+			wantMatches: []match{{0, 1, 200}, {1, 0.5, 200}},
+			wantGain:    300,
+			wantOpen:    []int{1, 2},
+			wantBasis:   300,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			res, err := Compute(l.txs, tt.method)
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			if len(res.Disposals) != 1 {
+				t.Fatalf("Compute() disposals = %d, want 1", len(res.Disposals))
+			}
+			d := res.Disposals[0]
+			if len(d.Matches) != len(tt.wantMatches) {
+				t.Fatalf("disposal matches = %+v, want %+v", d.Matches, tt.wantMatches)
+			}
+			for i, want := range tt.wantMatches {
+				got := d.Matches[i]
+				if got.LotTransactionID != l.ids[want.lot].Hex() || !near(got.Quantity, want.quantity) || !near(got.UnitCost, want.unitCost) {
+					t.Errorf("match %d = %+v, want lot %d, %v at %v", i, got, want.lot, want.quantity, want.unitCost)
+				}
+			}
+			if !near(d.Gain, tt.wantGain) {
+				t.Errorf("disposal gain = %v, want %v", d.Gain, tt.wantGain)
+			}
+
+			pos := res.Positions["bitcoin"]
+			if !near(pos.Quantity, 1.5) || !near(pos.CostBasis, tt.wantBasis) || !near(pos.RealizedGain, tt.wantGain) {
+				t.Errorf("position = %v units, basis %v, gain %v, want 1.5, %v, %v",
+					pos.Quantity, pos.CostBasis, pos.RealizedGain, tt.wantBasis, tt.wantGain)
+			}
+			if len(pos.Lots) != len(tt.wantOpen) {
+				t.Fatalf("open lots = %+v, want lots %v", pos.Lots, tt.wantOpen)
+			}
+			for i, lot := range tt.wantOpen {
+				if pos.Lots[i].TransactionID != l.ids[lot].Hex() {
+					t.Errorf("open lot %d = %s, want lot %d", i, pos.Lots[i].TransactionID, lot)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeQuantities(t *testing.T) {
+	tests := []struct {
+		name     string
+		ledger   *ledger
+		method   Method
+		wantQty  float64
+		wantLots int
+		wantErr  error
+	}{
+		{
+			name:     "partial lot",
+			ledger:   (&ledger{}).buy(2, 100).sell(0.5, 150),
+			method:   FIFO,
+			wantQty:  1.5,
+			wantLots: 1,
+		},
+		{
+			name:     "spread over several sales",
+			ledger:   (&ledger{}).buy(1, 100).sell(0.1, 150).sell(0.2, 150).sell(0.7, 150),
+			method:   FIFO,
+			wantQty:  0,
+			wantLots: 0,
+		},
+		{
+			// The sale's quantity sums the lots in a different order, so it
+			// differs from the running total by more than any absolute
+			// epsilon suited to whole coins
+			name: "full disposal of a large position",
+			ledger: (&ledger{}).
+				buy(123456789.123456, 0.00001).
+				buy(987654321.987654, 0.00002).
+				buy(0.3, 0.00003).
+				sell(0.3+987654321.987654+123456789.123456, 0.00004),
+			method:   FIFO,
+			wantQty:  0,
+			wantLots: 0,
+		},
+		{
+			// A huge disposal must not drop a small lot it never touched
+			name:     "untouched small lot",
+			ledger:   (&ledger{}).buy(0.000001, 1).buy(1e9, 2).sell(1e9, 3),
+			method:   HIFO,
+			wantQty:  0.000001,
+			wantLots: 1,
+		},
+		{
+			name:    "oversell",
+			ledger:  (&ledger{}).buy(1, 100).sell(1.5, 150),
+			method:  FIFO,
+			wantErr: ErrOversold,
+		},
+		{
+			name:    "fee without lots",
+			ledger:  (&ledger{}).add(models.TransactionFee, 0.01, 0),
+			method:  Average,
+			wantErr: ErrOversold,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Compute(tt.ledger.txs, tt.method)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Compute() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			pos := res.Positions["bitcoin"]
+			if !near(pos.Quantity, tt.wantQty) || len(pos.Lots) != tt.wantLots {
+				t.Errorf("position = %v units in %d lots, want %v in %d", pos.Quantity, len(pos.Lots), tt.wantQty, tt.wantLots)
+			}
+		})
+	}
+}
+
+func TestComputeInvalidMethod(t *testing.T) {
+	if _, err := Compute(nil, "newest"); err == nil {
+		t.Fatal("Compute() with an unknown method succeeded")
+	}
+}
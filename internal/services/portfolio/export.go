@@ -0,0 +1,190 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/export"
+)
+
+type ExportDataset string
+
+const (
+	ExportHoldings     ExportDataset = "holdings"
+	ExportTransactions ExportDataset = "transactions"
+	ExportSnapshots    ExportDataset = "snapshots"
+)
+
+var ErrInvalidDataset = errors.New("dataset must be holdings, transactions or snapshots")
+
+// exportPageSize is how many snapshots are read from storage at a time
+const exportPageSize = 500
+
+func (d ExportDataset) Valid() bool {
+	switch d {
+	case ExportHoldings, ExportTransactions, ExportSnapshots:
+		return true
+	}
+	return false
+}
+
+// ExportQuery selects what to export. Transactions and snapshots are
+// limited to [From, To); holdings are valued as of To, or now when it is
+// zero. Holdings as of a past To come from the ledger alone, since manual
+// holdings carry no date. Amounts are converted into Currency, which must
+// already be resolved with ResolveCurrency.
+type ExportQuery struct {
+	Dataset  ExportDataset
+	From     time.Time
+	To       time.Time
+	Currency string
+}
+
+// Export streams a dataset to w. Conversion uses current exchange rates, as
+// cost basis does; rows quoted in a currency without a rate are written with
+// empty converted columns. Errors found before the first row are returned
+// without anything having been written, so the caller can still report them.
+func (s *Service) Export(ctx context.Context, userID string, portfolioID string, query ExportQuery, w export.Writer) error {
+	if !query.Dataset.Valid() {
+		return ErrInvalidDataset
+	}
+	convert, err := s.exportConverter(query.Currency)
+	if err != nil {
+		return err
+	}
+	switch query.Dataset {
+	case ExportHoldings:
+		err = s.exportHoldings(ctx, userID, portfolioID, query, w)
+	case ExportTransactions:
+		err = s.exportTransactions(ctx, userID, portfolioID, query, convert, w)
+	case ExportSnapshots:
+		err = s.exportSnapshots(ctx, userID, portfolioID, query, convert, w)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// exportConverter returns a function restating an amount from one currency
+// into currency, memoizing rates. It checks up front that rates are
+// available at all.
+func (s *Service) exportConverter(currency string) (func(amount float64, from string) *float64, error) {
+	if _, err := s.marketService.ConversionRate("usd", currency); err != nil {
+		return nil, err
+	}
+	rates := map[string]*float64{}
+	return func(amount float64, from string) *float64 {
+		rate, ok := rates[from]
+		if !ok {
+			if r, err := s.marketService.ConversionRate(from, currency); err == nil {
+				rate = &r
+			}
+			rates[from] = rate
+		}
+		if rate == nil {
+			return nil
+		}
+		converted := amount * *rate
+		return &converted
+	}, nil
+}
+
+func (s *Service) exportHoldings(ctx context.Context, userID string, portfolioID string, query ExportQuery, w export.Writer) error {
+	asOf := query.To
+	var holdings []HoldingWithValue
+	var err error
+	if asOf.IsZero() {
+		asOf = time.Now()
+		holdings, _, err = s.GetHoldingsWithValue(ctx, userID, portfolioID, query.Currency)
+	} else {
+		holdings, _, err = s.GetHoldingsWithValueAt(ctx, userID, portfolioID, asOf, query.Currency)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := w.Header("asOf", "coinId", "source", "amount", "currency", "price", "value", "costBasis", "unrealizedPnl"); err != nil {
+		return err
+	}
+	for _, holding := range holdings {
+		source := "ledger"
+		if !holding.ID.IsZero() {
+			source = "manual"
+		}
+		var price, value *float64
+		if !holding.PriceUnavailable {
+			price, value = &holding.CurrentPrice, &holding.CurrentValue
+		}
+		if err := w.Row(asOf, holding.CoinID, source, holding.Amount, query.Currency,
+			price, value, holding.CostBasis, holding.UnrealizedPnL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) exportTransactions(ctx context.Context, userID string, portfolioID string, query ExportQuery, convert func(float64, string) *float64, w export.Writer) error {
+	wroteHeader := false
+	err := s.repo.EachTransaction(ctx, userID, portfolioID, query.From, query.To, func(tx models.Transaction) error {
+		if !wroteHeader {
+			if err := writeTransactionHeader(w); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+		unitPrice := convert(tx.UnitPrice, tx.QuoteCurrency)
+		var value *float64
+		if unitPrice != nil {
+			v := *unitPrice * tx.Quantity
+			value = &v
+		}
+		return w.Row(tx.Timestamp.Time(), tx.ID.Hex(), tx.CoinID, string(tx.Type), tx.IncomeType, tx.Quantity,
+			tx.UnitPrice, tx.QuoteCurrency, query.Currency, unitPrice, value, tx.ExternalID, tx.Notes)
+	})
+	if err != nil || wroteHeader {
+		return err
+	}
+	return writeTransactionHeader(w)
+}
+
+func writeTransactionHeader(w export.Writer) error {
+	return w.Header("timestamp", "id", "coinId", "type", "incomeType", "quantity", "unitPrice", "quoteCurrency",
+		"currency", "convertedUnitPrice", "convertedValue", "externalId", "notes")
+}
+
+// exportSnapshots pages through snapshot history by keyset so only one page
+// is held at a time
+func (s *Service) exportSnapshots(ctx context.Context, userID string, portfolioID string, query ExportQuery, convert func(float64, string) *float64, w export.Writer) error {
+	page := SnapshotQuery{From: query.From, To: query.To, Limit: exportPageSize}
+	for first := true; ; first = false {
+		snapshots, err := s.repo.ListSnapshots(ctx, userID, portfolioID, page)
+		if err != nil {
+			return err
+		}
+		if first {
+			if err := w.Header("timestamp", "id", "source", "snapshotCurrency", "snapshotValue",
+				"currency", "convertedValue", "priceSource"); err != nil {
+				return err
+			}
+		}
+		for _, snapshot := range snapshots {
+			// Snapshots stored before currencies were recorded are in USD
+			currency := snapshot.Currency
+			if currency == "" {
+				currency = "usd"
+			}
+			if err := w.Row(snapshot.Timestamp.Time(), snapshot.ID.Hex(), snapshot.Source, currency,
+				snapshot.TotalValue, query.Currency, convert(snapshot.TotalValue, currency), snapshot.PriceSource); err != nil {
+				return err
+			}
+		}
+		if len(snapshots) < exportPageSize {
+			return nil
+		}
+		last := snapshots[len(snapshots)-1]
+		page.After = &SnapshotCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+}
@@ -0,0 +1,227 @@
+// Package export encodes tabular rows for download. Every format writes
+// rows as they arrive and flushes periodically, so an export never has to
+// be held in memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	// FormatExcel is CSV that spreadsheet apps open cleanly: a UTF-8 byte
+	// order mark, CRLF line endings, plain date-times and text cells guarded
+	// against formula injection
+	FormatExcel = "excel"
+)
+
+// flushEvery is how many rows are written between flushes to the client
+const flushEvery = 500
+
+var ErrUnknownFormat = errors.New("format must be csv, jsonl or excel")
+
+// formatAliases maps accepted spellings to a format
+var formatAliases = map[string]string{
+	"":       FormatCSV,
+	"csv":    FormatCSV,
+	"jsonl":  FormatJSONL,
+	"ndjson": FormatJSONL,
+	"excel":  FormatExcel,
+	"xlsx":   FormatExcel,
+}
+
+// ParseFormat resolves a requested format name, defaulting to CSV
+func ParseFormat(name string) (string, error) {
+	format, ok := formatAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+	return format, nil
+}
+
+func ContentType(format string) string {
+	if format == FormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func Extension(format string) string {
+	if format == FormatJSONL {
+		return "jsonl"
+	}
+	return "csv"
+}
+
+// Writer encodes one table. Nothing reaches the underlying writer before
+// Header, which is called once ahead of any Row; cells
+// are strings, float64, int, bool, time.Time or *float64, with nil pointers
+// written as empty cells.
+type Writer interface {
+	Header(columns ...string) error
+	Row(cells ...any) error
+	// Close flushes anything still buffered
+	Close() error
+}
+
+// NewWriter returns a Writer for format that writes to w, flushing it as
+// well when w is an http.Flusher
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{csv: csv.NewWriter(w), dst: w}, nil
+	case FormatExcel:
+		cw := csv.NewWriter(w)
+		cw.UseCRLF = true
+		return &csvWriter{csv: cw, dst: w, excel: true}, nil
+	case FormatJSONL:
+		return &jsonlWriter{buf: bufio.NewWriter(w), dst: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+func flushClient(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type csvWriter struct {
+	csv   *csv.Writer
+	dst   io.Writer
+	excel bool
+	rows  int
+	cells []string
+}
+
+func (w *csvWriter) Header(columns ...string) error {
+	if w.excel {
+		if _, err := io.WriteString(w.dst, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(columns)
+}
+
+func (w *csvWriter) Row(cells ...any) error {
+	w.cells = w.cells[:0]
+	for _, cell := range cells {
+		w.cells = append(w.cells, w.format(cell))
+	}
+	if err := w.csv.Write(w.cells); err != nil {
+		return err
+	}
+	if w.rows++; w.rows%flushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	return w.flush()
+}
+
+func (w *csvWriter) flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	flushClient(w.dst)
+	return nil
+}
+
+func (w *csvWriter) format(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		if w.excel && v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		if w.excel {
+			return v.UTC().Format("2006-01-02 15:04:05")
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
+
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	dst     io.Writer
+	columns []string
+	rows    int
+}
+
+func (w *jsonlWriter) Header(columns ...string) error {
+	w.columns = columns
+	return nil
+}
+
+// Row writes one object per line keyed by the header columns, keeping the
+// column order
+func (w *jsonlWriter) Row(cells ...any) error {
+	w.buf.WriteByte('{')
+	for i, cell := range cells {
+		if i >= len(w.columns) {
+			break
+		}
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(w.columns[i])
+		w.buf.Write(key)
+		w.buf.WriteByte(':')
+		if t, ok := cell.(time.Time); ok && t.IsZero() {
+			cell = nil
+		}
+		value, err := json.Marshal(cell)
+		if err != nil {
+			return err
+		}
+		w.buf.Write(value)
+	}
+	w.buf.WriteString("}\n")
+	if w.rows++; w.rows%flushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.flush()
+}
+
+func (w *jsonlWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushClient(w.dst)
+	return nil
+}
@@ -0,0 +1,153 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+)
+
+type (
+	SnapshotQuery  = repository.SnapshotQuery
+	SnapshotCursor = repository.SnapshotCursor
+)
+
+// Bucket sizes accepted by SnapshotBuckets. Days start at UTC midnight and
+// weeks on Monday.
+var BucketSizes = map[string]time.Duration{
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+	"1w": 7 * 24 * time.Hour,
+}
+
+var ErrInvalidBucket = errors.New("bucket must be 1h, 1d or 1w")
+
+// ListSnapshots returns one page of snapshots and the cursor for the next
+// page, which is nil on the last page
+func (s *Service) ListSnapshots(ctx context.Context, userID string, portfolioID string, query SnapshotQuery) ([]models.Snapshot, *SnapshotCursor, error) {
+	limit := query.Limit
+	if limit > 0 {
+		query.Limit = limit + 1
+	}
+	snapshots, err := s.repo.ListSnapshots(ctx, userID, portfolioID, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit <= 0 || len(snapshots) <= limit {
+		return snapshots, nil, nil
+	}
+	snapshots = snapshots[:limit]
+	last := snapshots[limit-1]
+	return snapshots, &SnapshotCursor{Timestamp: last.Timestamp, ID: last.ID}, nil
+}
+
+// snapshotBatchSize bounds each read while bucketing snapshots
+const snapshotBatchSize = 500
+
+// SnapshotBucket summarises the snapshots in one currency that fall in one
+// time bucket
+type SnapshotBucket struct {
+	Start    primitive.DateTime `json:"start"`
+	Currency string             `json:"currency"`
+	Open     float64            `json:"open"`
+	High     float64            `json:"high"`
+	Low      float64            `json:"low"`
+	Close    float64            `json:"close"`
+	LastAt   primitive.DateTime `json:"lastAt"` // timestamp of the closing snapshot
+	Count    int                `json:"count"`
+}
+
+// snapshotCurrency is the currency a snapshot's values are in. Snapshots
+// from before the currency was recorded were valued in USD.
+func snapshotCurrency(snapshot models.Snapshot) string {
+	if snapshot.Currency == "" {
+		return "usd"
+	}
+	return snapshot.Currency
+}
+
+// SnapshotBuckets downsamples snapshots into fixed buckets of TotalValue,
+// one per currency the snapshots in a period were valued in. Limit and the
+// returned cursor count periods rather than snapshots; the cursor carries no
+// ID and marks the time boundary of the next page. Snapshots are read in
+// batches only until the page is full.
+func (s *Service) SnapshotBuckets(ctx context.Context, userID string, portfolioID string, query SnapshotQuery, bucket string) ([]SnapshotBucket, *SnapshotCursor, error) {
+	size, ok := BucketSizes[bucket]
+	if !ok {
+		return nil, nil, ErrInvalidBucket
+	}
+	limit := query.Limit
+	query.Limit = snapshotBatchSize
+
+	var buckets, period []SnapshotBucket
+	var periodStart time.Time
+	periods := 0
+	flush := func() {
+		sort.Slice(period, func(i, j int) bool { return period[i].Currency < period[j].Currency })
+		buckets = append(buckets, period...)
+		period = nil
+		periods++
+	}
+	for {
+		snapshots, err := s.repo.ListSnapshots(ctx, userID, portfolioID, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, snapshot := range snapshots {
+			start := snapshot.Timestamp.Time().UTC().Truncate(size)
+			if len(period) == 0 || !start.Equal(periodStart) {
+				if len(period) > 0 {
+					flush()
+				}
+				if limit > 0 && periods == limit {
+					// Resume from the boundary of the last period returned
+					next := models.ToPrimitiveDateTime(periodStart.Add(size))
+					if query.Descending {
+						next = models.ToPrimitiveDateTime(periodStart)
+					}
+					return buckets, &SnapshotCursor{Timestamp: next}, nil
+				}
+				periodStart = start
+			}
+
+			currency := snapshotCurrency(snapshot)
+			i := slices.IndexFunc(period, func(b SnapshotBucket) bool { return b.Currency == currency })
+			if i < 0 {
+				i = len(period)
+				period = append(period, SnapshotBucket{
+					Start:    models.ToPrimitiveDateTime(start),
+					Currency: currency,
+					Open:     snapshot.TotalValue,
+					High:     snapshot.TotalValue,
+					Low:      snapshot.TotalValue,
+				})
+			}
+			b := &period[i]
+			b.High = max(b.High, snapshot.TotalValue)
+			b.Low = min(b.Low, snapshot.TotalValue)
+			b.Count++
+			// Descending pages meet each bucket's closing snapshot first
+			if !query.Descending || b.Count == 1 {
+				b.Close = snapshot.TotalValue
+				b.LastAt = snapshot.Timestamp
+			} else {
+				b.Open = snapshot.TotalValue
+			}
+		}
+		if len(snapshots) < snapshotBatchSize {
+			break
+		}
+		last := snapshots[len(snapshots)-1]
+		query.After = &SnapshotCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+	if len(period) > 0 {
+		flush()
+	}
+	return buckets, nil, nil
+}
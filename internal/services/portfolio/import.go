@@ -0,0 +1,327 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/importer"
+)
+
+var ErrImportHasErrors = errors.New("import has rows with errors")
+
+type ImportStatus string
+
+const (
+	ImportReady     ImportStatus = "ready"
+	ImportDuplicate ImportStatus = "duplicate"
+	ImportError     ImportStatus = "error"
+)
+
+type ImportOptions struct {
+	// Format is one of importer.Formats; empty detects it from the header
+	Format  string
+	Mapping *importer.Mapping
+	// Symbols maps file symbols to coin IDs, overriding catalog lookups
+	Symbols map[string]string
+	Commit  bool
+	// SkipErrors commits the ready rows even when others have errors
+	SkipErrors bool
+}
+
+type ImportRow struct {
+	importer.Record
+	CoinID string       `json:"coinId,omitempty"`
+	Status ImportStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+type ImportSummary struct {
+	Rows         int `json:"rows"`
+	Ready        int `json:"ready"`
+	Duplicates   int `json:"duplicates"`
+	Errors       int `json:"errors"`
+	Transactions int `json:"transactions"`
+	Holdings     int `json:"holdings"`
+}
+
+// ImportPreview lists what an import found. LedgerError is set when the
+// ready transactions would take a balance below zero.
+type ImportPreview struct {
+	Format      string        `json:"format"`
+	Committed   bool          `json:"committed"`
+	Summary     ImportSummary `json:"summary"`
+	LedgerError string        `json:"ledgerError,omitempty"`
+	Rows        []ImportRow   `json:"rows"`
+}
+
+// Import reads a CSV export into a portfolio. Without opts.Commit it only
+// previews; a commit writes every ready row in one batch, and is refused
+// while rows have errors (unless opts.SkipErrors) or the ledger would not
+// balance. Rows already in the portfolio, matched by exchange ID or by coin,
+// type, quantity, price and time, are reported as duplicates and skipped.
+// Holding rows are errors when the ledger has entries for the coin.
+// Within the file only exchange IDs are matched, since an exchange may
+// report identical fills in the same second. Trades quoted in a coin are
+// restated in USD at that coin's stored price when the trade happened.
+func (s *Service) Import(ctx context.Context, userID string, portfolioID string, r io.Reader, opts ImportOptions) (*ImportPreview, error) {
+	parsed, err := importer.Parse(r, opts.Format, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Commit {
+		unlock, err := s.repo.LockLedger(ctx, userID, portfolioID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	existingHoldings, err := s.repo.ListHoldings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	existingTransactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]bool)
+	for _, holding := range existingHoldings {
+		stored[holdingKey(holding.CoinID)] = true
+	}
+	for _, tx := range existingTransactions {
+		for _, key := range transactionKeys(tx) {
+			stored[key] = true
+		}
+	}
+	inFile := make(map[string]bool)
+
+	preview := &ImportPreview{Format: parsed.Format}
+	resolved := make(map[string]string)
+	var holdings []models.Holding
+	var holdingRows []int // index in preview.Rows of each holding
+	var transactions []models.Transaction
+	for _, record := range parsed.Records {
+		row := ImportRow{Record: record, Status: ImportReady}
+		coinID, err := s.resolveImportSymbol(record.Symbol, opts.Symbols, resolved)
+		if errors.Is(err, ErrCatalogUnavailable) {
+			return nil, err
+		}
+		if err != nil {
+			row.Status, row.Error = ImportError, err.Error()
+			preview.Rows = append(preview.Rows, row)
+			continue
+		}
+		row.CoinID = coinID
+
+		var keys []string
+		var tx models.Transaction
+		if record.Kind == importer.KindHolding {
+			keys = []string{holdingKey(coinID)}
+		} else {
+			record, err = s.valueInFiat(ctx, record, opts.Symbols, resolved)
+			if errors.Is(err, ErrCatalogUnavailable) {
+				return nil, err
+			}
+			if err != nil {
+				row.Status, row.Error = ImportError, err.Error()
+				preview.Rows = append(preview.Rows, row)
+				continue
+			}
+			row.Record = record
+			tx, err = s.normalizeTransaction(models.Transaction{
+				UserID:        userID,
+				PortfolioID:   portfolioID,
+				CoinID:        coinID,
+				Type:          record.Type,
+				IncomeType:    record.IncomeType,
+				Quantity:      record.Quantity,
+				UnitPrice:     record.UnitPrice,
+				QuoteCurrency: record.QuoteCurrency,
+				Timestamp:     models.ToPrimitiveDateTime(record.Timestamp),
+				Notes:         record.Notes,
+				ExternalID:    record.ExternalID,
+			})
+			if err != nil {
+				row.Status, row.Error = ImportError, err.Error()
+				preview.Rows = append(preview.Rows, row)
+				continue
+			}
+			keys = transactionKeys(tx)
+		}
+
+		duplicate := false
+		for _, key := range keys {
+			duplicate = duplicate || stored[key] || inFile[key]
+			if !strings.HasPrefix(key, contentKeyPrefix) {
+				inFile[key] = true
+			}
+		}
+		if duplicate {
+			row.Status = ImportDuplicate
+		} else if record.Kind == importer.KindHolding {
+			holdingRows = append(holdingRows, len(preview.Rows))
+			holdings = append(holdings, models.Holding{
+				UserID:      userID,
+				PortfolioID: portfolioID,
+				CoinID:      coinID,
+				Amount:      record.Quantity,
+				Version:     1,
+			})
+		} else {
+			transactions = append(transactions, tx)
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+	// Manual holdings of coins the ledger tracks, before or after this
+	// import, would be hidden behind the ledger position
+	inLedger := ledgerCoins(append(existingTransactions, transactions...))
+	manual := holdings[:0]
+	for i, holding := range holdings {
+		if inLedger[holding.CoinID] {
+			row := &preview.Rows[holdingRows[i]]
+			row.Status, row.Error = ImportError, fmt.Errorf("%w: %s", ErrCoinInLedger, holding.CoinID).Error()
+			continue
+		}
+		manual = append(manual, holding)
+	}
+	holdings = manual
+	for _, rowErr := range parsed.Errors {
+		preview.Rows = append(preview.Rows, ImportRow{
+			Record: importer.Record{Line: rowErr.Line},
+			Status: ImportError,
+			Error:  rowErr.Error,
+		})
+	}
+	sort.SliceStable(preview.Rows, func(i, j int) bool {
+		return preview.Rows[i].Line < preview.Rows[j].Line
+	})
+
+	preview.Summary = summarize(preview.Rows)
+	ledgerErr := checkLedger(append(existingTransactions, transactions...))
+	if ledgerErr != nil {
+		preview.LedgerError = ledgerErr.Error()
+	}
+	if !opts.Commit {
+		return preview, nil
+	}
+
+	if preview.Summary.Errors > 0 && !opts.SkipErrors {
+		return preview, ErrImportHasErrors
+	}
+	if ledgerErr != nil {
+		return preview, ledgerErr
+	}
+	for i := range holdings {
+		holdings[i].ID = primitive.NewObjectID()
+	}
+	for i := range transactions {
+		transactions[i].ID = primitive.NewObjectID()
+	}
+	if err := s.repo.ImportRecords(ctx, holdings, transactions); err != nil {
+		return nil, err
+	}
+	preview.Committed = true
+	return preview, nil
+}
+
+// resolveImportSymbol maps a file symbol to a coin ID, trying the caller's
+// overrides before the catalog. Results are memoized in resolved.
+func (s *Service) resolveImportSymbol(symbol string, overrides map[string]string, resolved map[string]string) (string, error) {
+	symbol = strings.ToLower(strings.TrimSpace(symbol))
+	if coinID, ok := resolved[symbol]; ok {
+		return coinID, nil
+	}
+	for from, to := range overrides {
+		if strings.EqualFold(strings.TrimSpace(from), symbol) {
+			coinID := strings.ToLower(strings.TrimSpace(to))
+			if err := s.catalog.Validate(coinID); err != nil {
+				return "", err
+			}
+			resolved[symbol] = coinID
+			return coinID, nil
+		}
+	}
+	coinID, err := s.catalog.Resolve(symbol)
+	if err != nil {
+		return "", err
+	}
+	resolved[symbol] = coinID
+	return coinID, nil
+}
+
+// valueInFiat restates a transaction quoted in a coin, such as either leg of
+// an ETH/BTC trade, in USD at the quote coin's stored price at the time of
+// the trade. Records quoted in a supported currency are returned unchanged;
+// other fiat quotes are rejected rather than looked up as coin tickers.
+func (s *Service) valueInFiat(ctx context.Context, record importer.Record, overrides map[string]string, resolved map[string]string) (importer.Record, error) {
+	quote := strings.ToLower(strings.TrimSpace(record.QuoteCurrency))
+	if quote == "" {
+		return record, nil
+	}
+	if _, err := s.marketService.NormalizeCurrency(quote); err == nil {
+		return record, nil
+	}
+	if importer.IsFiat(quote) {
+		return record, fmt.Errorf("unsupported quote currency %s", quote)
+	}
+	quoteCoin, err := s.resolveImportSymbol(quote, overrides, resolved)
+	if errors.Is(err, ErrCatalogUnavailable) {
+		return record, err
+	}
+	if err != nil {
+		return record, fmt.Errorf("quote %s is neither a supported currency nor a known coin", quote)
+	}
+	price, err := s.priceHistory.PriceAt(ctx, quoteCoin, record.Timestamp)
+	if err != nil {
+		return record, fmt.Errorf("cannot value %s quote in usd: %w", quote, err)
+	}
+	record.UnitPrice *= price.Price
+	record.QuoteCurrency = "usd"
+	return record, nil
+}
+
+// contentKeyPrefix marks the transactionKeys entry built from a ledger
+// entry's contents rather than its exchange ID
+const contentKeyPrefix = "tx:"
+
+func holdingKey(coinID string) string {
+	return "holding:" + coinID
+}
+
+// transactionKeys identify a ledger entry for duplicate detection: its
+// exchange ID when it has one, and always its contents
+func transactionKeys(tx models.Transaction) []string {
+	keys := []string{fmt.Sprintf(contentKeyPrefix+"%s:%s:%.10g:%.6g:%d",
+		tx.CoinID, tx.Type, tx.Quantity, tx.UnitPrice, tx.Timestamp.Time().Unix())}
+	if tx.ExternalID != "" {
+		keys = append(keys, "ext:"+tx.ExternalID)
+	}
+	return keys
+}
+
+func summarize(rows []ImportRow) ImportSummary {
+	summary := ImportSummary{Rows: len(rows)}
+	for _, row := range rows {
+		switch row.Status {
+		case ImportReady:
+			summary.Ready++
+			if row.Kind == importer.KindHolding {
+				summary.Holdings++
+			} else {
+				summary.Transactions++
+			}
+		case ImportDuplicate:
+			summary.Duplicates++
+		case ImportError:
+			summary.Errors++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,85 @@
+package importer
+
+import "github.com/faisal/crypto/backend/internal/models"
+
+// binanceParser reads the Binance spot trade history export, in both its
+// current layout (Pair, Side, Executed, Amount with asset suffixes) and the
+// older one (Market, Type, Amount, Total, Fee Coin)
+type binanceParser struct{}
+
+func (binanceParser) Name() string { return "binance" }
+
+func (binanceParser) Detect(header []string) bool {
+	return hasColumns(header, "date(utc)", "pair", "side", "executed", "amount") ||
+		hasColumns(header, "date(utc)", "market", "type", "amount", "total")
+}
+
+func (binanceParser) Parse(row Row) ([]Record, error) {
+	at, err := parseTime(row.Get("date(utc)"), "")
+	if err != nil {
+		return nil, err
+	}
+	side, err := parseSide(row.Get("side", "type"))
+	if err != nil {
+		return nil, err
+	}
+
+	var qty, total, feeAmount float64
+	var base, quote, feeAsset string
+	if pair := row.Get("pair"); pair != "" {
+		if qty, base, err = parseAmount(row.Get("executed")); err != nil {
+			return nil, err
+		}
+		if total, quote, err = parseAmount(row.Get("amount")); err != nil {
+			return nil, err
+		}
+		if value := row.Get("fee"); value != "" {
+			if feeAmount, feeAsset, err = parseAmount(value); err != nil {
+				return nil, err
+			}
+		}
+		if base == "" || quote == "" {
+			if base, quote, err = splitPair(pair); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if base, quote, err = splitPair(row.Get("market")); err != nil {
+			return nil, err
+		}
+		if qty, _, err = parseAmount(row.Get("amount")); err != nil {
+			return nil, err
+		}
+		if total, _, err = parseAmount(row.Get("total")); err != nil {
+			return nil, err
+		}
+		if feeAmount, err = parseOptional(row.Get("fee")); err != nil {
+			return nil, err
+		}
+		feeAsset = row.Get("fee coin")
+	}
+
+	var coinFee *Record
+	if feeAmount > 0 {
+		switch {
+		case feeAsset == "" || feeAsset == quote:
+			if side == models.TransactionBuy {
+				total += feeAmount
+			} else {
+				total -= feeAmount
+			}
+		default:
+			r := fee(feeAsset, feeAmount, at, "")
+			coinFee = &r
+		}
+	}
+
+	records, err := trade(side, base, quote, qty, total, at, "")
+	if err != nil {
+		return nil, err
+	}
+	if coinFee != nil {
+		records = append(records, *coinFee)
+	}
+	return records, nil
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// convertPattern reads the note Coinbase writes on conversions, such as
+// "Converted 0.5 ETH to 0.02 BTC"
+var convertPattern = regexp.MustCompile(`(?i)converted\s+([0-9.,]+)\s+(\S+)\s+to\s+([0-9.,]+)\s+(\S+)`)
+
+// coinbaseIncome maps the transaction types Coinbase uses for earnings to
+// income categories
+var coinbaseIncome = map[string]string{
+	"rewards income":   models.IncomeReward,
+	"staking income":   models.IncomeStaking,
+	"inflation reward": models.IncomeStaking,
+	"learning reward":  models.IncomeReward,
+	"coinbase earn":    models.IncomeReward,
+	"interest":         models.IncomeInterest,
+	"airdrop":          models.IncomeAirdrop,
+}
+
+// coinbaseParser reads the Coinbase transaction history export. Rows for
+// fiat deposits and withdrawals are skipped.
+type coinbaseParser struct{}
+
+func (coinbaseParser) Name() string { return "coinbase" }
+
+func (coinbaseParser) Detect(header []string) bool {
+	return hasColumns(header, "timestamp", "transaction type", "asset", "quantity transacted")
+}
+
+func (coinbaseParser) Parse(row Row) ([]Record, error) {
+	asset := strings.ToUpper(row.Get("asset"))
+	if fiatCurrencies[strings.ToLower(asset)] {
+		return nil, nil
+	}
+	at, err := parseTime(row.Get("timestamp"), "")
+	if err != nil {
+		return nil, err
+	}
+	qty, _, err := parseAmount(row.Get("quantity transacted"))
+	if err != nil {
+		return nil, err
+	}
+	qty = math.Abs(qty)
+	price, err := parseOptional(row.Get("price at transaction", "spot price at transaction"))
+	if err != nil {
+		return nil, err
+	}
+	subtotal, err := parseOptional(row.Get("subtotal"))
+	if err != nil {
+		return nil, err
+	}
+	total, err := parseOptional(row.Get("total (inclusive of fees and/or spread)", "total"))
+	if err != nil {
+		return nil, err
+	}
+	subtotal, total = math.Abs(subtotal), math.Abs(total)
+	quote := row.Get("price currency", "spot price currency")
+	if quote == "" {
+		quote = "USD"
+	}
+	id := row.Get("id")
+	notes := row.Get("notes")
+
+	kind := strings.ToLower(row.Get("transaction type"))
+	switch kind {
+	case "buy", "advanced trade buy":
+		if total == 0 {
+			total = price * qty
+		}
+		return trade(models.TransactionBuy, asset, quote, qty, total, at, id)
+	case "sell", "advanced trade sell":
+		if total == 0 {
+			total = price * qty
+		}
+		return trade(models.TransactionSell, asset, quote, qty, total, at, id)
+	case "convert":
+		return convert(row, asset, quote, qty, subtotal, total, at, id)
+	}
+
+	txType, incomeType := models.TransactionIncome, coinbaseIncome[kind]
+	switch {
+	case incomeType != "":
+	case kind == "receive" || kind == "deposit":
+		txType = models.TransactionTransferIn
+	case kind == "send" || kind == "withdrawal":
+		txType = models.TransactionTransferOut
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %q", row.Get("transaction type"))
+	}
+	if qty <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	currency, _ := settlement(quote)
+	return []Record{{
+		Kind:          KindTransaction,
+		Symbol:        asset,
+		Type:          txType,
+		IncomeType:    incomeType,
+		Quantity:      qty,
+		UnitPrice:     price,
+		QuoteCurrency: currency,
+		Timestamp:     at,
+		ExternalID:    id,
+		Notes:         notes,
+	}}, nil
+}
+
+// convert splits a conversion into a sale of the source asset and a purchase
+// of the target, both at the conversion's value
+func convert(row Row, asset, quote string, qty, subtotal, total float64, at time.Time, id string) ([]Record, error) {
+	match := convertPattern.FindStringSubmatch(row.Get("notes"))
+	if match == nil {
+		return nil, fmt.Errorf("conversion notes do not name the target asset")
+	}
+	target := strings.ToUpper(match[4])
+	received, _, err := parseAmount(match[3])
+	if err != nil {
+		return nil, err
+	}
+	value := subtotal
+	if value == 0 {
+		value = total
+	}
+	sold, err := trade(models.TransactionSell, asset, quote, qty, value, at, id)
+	if err != nil {
+		return nil, err
+	}
+	bought, err := trade(models.TransactionBuy, target, quote, received, value, at, legID(id, strings.ToLower(target)))
+	if err != nil {
+		return nil, err
+	}
+	return append(sold, bought...), nil
+}
@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// Mapping names the CSV columns the generic format reads. Empty fields fall
+// back to common column names. Rows without a type column use DefaultType,
+// and are holdings when that is empty too.
+type Mapping struct {
+	Date        string `json:"date"`
+	Type        string `json:"type"`
+	Symbol      string `json:"symbol"`
+	Quantity    string `json:"quantity"`
+	Price       string `json:"price"`
+	Total       string `json:"total"`
+	Quote       string `json:"quote"`
+	Fee         string `json:"fee"`
+	ID          string `json:"id"`
+	Notes       string `json:"notes"`
+	DateFormat  string `json:"dateFormat"`
+	DefaultType string `json:"defaultType"`
+}
+
+type genericParser struct {
+	mapping Mapping
+	date    []string
+	kind    []string
+	symbol  []string
+	qty     []string
+	price   []string
+	total   []string
+	quote   []string
+	fee     []string
+	id      []string
+	notes   []string
+}
+
+func newGenericParser(mapping *Mapping) *genericParser {
+	m := Mapping{}
+	if mapping != nil {
+		m = *mapping
+	}
+	return &genericParser{
+		mapping: m,
+		date:    columns(m.Date, "date", "timestamp", "time", "datetime"),
+		kind:    columns(m.Type, "type", "side", "transaction type"),
+		symbol:  columns(m.Symbol, "symbol", "coin", "asset", "coin_id", "coinid", "ticker"),
+		qty:     columns(m.Quantity, "quantity", "amount", "qty", "balance"),
+		price:   columns(m.Price, "price", "unit price", "unit_price", "unitprice"),
+		total:   columns(m.Total, "total", "cost", "value"),
+		quote:   columns(m.Quote, "quote", "quote currency", "quote_currency", "quotecurrency", "currency"),
+		fee:     columns(m.Fee, "fee", "fees"),
+		id:      columns(m.ID, "id", "txid", "transaction id"),
+		notes:   columns(m.Notes, "notes", "note", "description"),
+	}
+}
+
+// columns is the mapped column when set, else the defaults
+func columns(mapped string, defaults ...string) []string {
+	if mapped = strings.ToLower(strings.TrimSpace(mapped)); mapped != "" {
+		return []string{mapped}
+	}
+	return defaults
+}
+
+func (p *genericParser) Name() string { return FormatGeneric }
+
+func (p *genericParser) Detect(header []string) bool {
+	return anyColumn(header, p.symbol) && anyColumn(header, p.qty)
+}
+
+func anyColumn(header []string, names []string) bool {
+	for _, name := range names {
+		if hasColumns(header, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *genericParser) Parse(row Row) ([]Record, error) {
+	symbol := strings.TrimSpace(row.Get(p.symbol...))
+	if symbol == "" {
+		return nil, fmt.Errorf("missing symbol")
+	}
+	qty, _, err := parseAmount(row.Get(p.qty...))
+	if err != nil {
+		return nil, err
+	}
+
+	kind := strings.ToLower(row.Get(p.kind...))
+	if kind == "" {
+		kind = strings.ToLower(p.mapping.DefaultType)
+	}
+	if kind == "" || kind == "holding" || kind == "balance" {
+		if qty <= 0 {
+			return nil, fmt.Errorf("quantity must be positive")
+		}
+		return []Record{{Kind: KindHolding, Symbol: symbol, Quantity: qty}}, nil
+	}
+
+	txType, incomeType, err := genericType(kind)
+	if err != nil {
+		return nil, err
+	}
+	at, err := parseTime(row.Get(p.date...), p.mapping.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	price, err := parseOptional(row.Get(p.price...))
+	if err != nil {
+		return nil, err
+	}
+	total, err := parseOptional(row.Get(p.total...))
+	if err != nil {
+		return nil, err
+	}
+	feeAmount, err := parseOptional(row.Get(p.fee...))
+	if err != nil {
+		return nil, err
+	}
+	quote := row.Get(p.quote...)
+	if quote == "" {
+		quote = "USD"
+	}
+	id := row.Get(p.id...)
+
+	if txType == models.TransactionBuy || txType == models.TransactionSell {
+		if price == 0 && total == 0 {
+			return nil, fmt.Errorf("price or total is required for %s rows", txType)
+		}
+		if total == 0 {
+			total = price * qty
+		}
+		if txType == models.TransactionBuy {
+			total += feeAmount
+		} else {
+			total -= feeAmount
+		}
+		records, err := trade(txType, symbol, quote, qty, total, at, id)
+		if err != nil {
+			return nil, err
+		}
+		records[0].Notes = row.Get(p.notes...)
+		return records, nil
+	}
+
+	if qty <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	if price == 0 && total > 0 {
+		price = total / qty
+	}
+	currency, _ := settlement(quote)
+	return []Record{{
+		Kind:          KindTransaction,
+		Symbol:        symbol,
+		Type:          txType,
+		IncomeType:    incomeType,
+		Quantity:      qty,
+		UnitPrice:     price,
+		QuoteCurrency: currency,
+		Timestamp:     at,
+		ExternalID:    id,
+		Notes:         row.Get(p.notes...),
+	}}, nil
+}
+
+// genericType reads a type column, returning the income category as well
+// for income rows
+func genericType(kind string) (models.TransactionType, string, error) {
+	switch kind {
+	case "buy", "sell":
+		return models.TransactionType(kind), "", nil
+	case "transfer_in", "transfer in", "deposit", "receive":
+		return models.TransactionTransferIn, "", nil
+	case "transfer_out", "transfer out", "withdrawal", "send":
+		return models.TransactionTransferOut, "", nil
+	case "fee":
+		return models.TransactionFee, "", nil
+	case "income":
+		return models.TransactionIncome, models.IncomeReward, nil
+	}
+	if models.ValidIncomeType(kind) {
+		return models.TransactionIncome, kind, nil
+	}
+	return "", "", fmt.Errorf("unsupported type %q", kind)
+}
@@ -0,0 +1,239 @@
+// Package importer turns exchange CSV exports into ledger records. Each
+// supported export has a Parser that recognises its header row; anything
+// else can be read with a generic column mapping.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// MaxRows caps the data rows read from one file
+const MaxRows = 10000
+
+// headerSearchLines is how far into a file the header row is looked for;
+// some exports put a preamble above it
+const headerSearchLines = 10
+
+const (
+	FormatAuto    = "auto"
+	FormatGeneric = "generic"
+)
+
+var (
+	ErrUnknownFormat      = errors.New("unknown import format")
+	ErrUnrecognizedFormat = errors.New("unrecognized CSV header")
+	ErrTooManyRows        = fmt.Errorf("import is limited to %d rows", MaxRows)
+)
+
+type Kind string
+
+const (
+	KindTransaction Kind = "transaction"
+	KindHolding     Kind = "holding"
+)
+
+// Record is one ledger entry or holding read from a file. Symbol is the
+// asset as the exchange names it; mapping it to a coin ID is left to the
+// caller. Holdings carry only Symbol and Quantity.
+type Record struct {
+	Line          int                    `json:"line"`
+	Kind          Kind                   `json:"kind"`
+	Symbol        string                 `json:"symbol"`
+	Type          models.TransactionType `json:"type,omitempty"`
+	IncomeType    string                 `json:"incomeType,omitempty"`
+	Quantity      float64                `json:"quantity"`
+	UnitPrice     float64                `json:"unitPrice,omitempty"`
+	QuoteCurrency string                 `json:"quoteCurrency,omitempty"`
+	Timestamp     time.Time              `json:"timestamp,omitzero"`
+	ExternalID    string                 `json:"externalId,omitempty"`
+	Notes         string                 `json:"notes,omitempty"`
+}
+
+// RowError is a data row that could not be parsed
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Row is a data row keyed by its lowercased header names
+type Row struct {
+	Line   int
+	fields map[string]string
+}
+
+// Get returns the first non-empty value among the named columns
+func (r Row) Get(names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(r.fields[strings.ToLower(name)]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+type Parser interface {
+	Name() string
+	// Detect reports whether header, lowercased and trimmed, is this
+	// format's header row
+	Detect(header []string) bool
+	// Parse converts a data row. A row may yield several records, such as
+	// both legs of a crypto-to-crypto trade, or none for rows that do not
+	// move a balance.
+	Parse(row Row) ([]Record, error)
+}
+
+// parsers are the exchange formats tried, in order, when detecting
+var parsers = []Parser{binanceParser{}, coinbaseParser{}, krakenParser{}}
+
+// Formats lists the accepted format names
+func Formats() []string {
+	names := []string{FormatAuto}
+	for _, p := range parsers {
+		names = append(names, p.Name())
+	}
+	return append(names, FormatGeneric)
+}
+
+type Result struct {
+	Format  string
+	Records []Record
+	Errors  []RowError
+}
+
+// Parse reads a CSV export. format is one of Formats, empty meaning auto;
+// mapping configures the generic format and may be nil to use its default
+// column names.
+func Parse(r io.Reader, format string, mapping *Mapping) (*Result, error) {
+	candidates, err := candidateParsers(format, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var parser Parser
+	var header []string
+	for searched := 0; parser == nil; searched++ {
+		if searched == headerSearchLines {
+			return nil, ErrUnrecognizedFormat
+		}
+		line, err := reader.Read()
+		if err == io.EOF {
+			return nil, ErrUnrecognizedFormat
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		header = normalizeHeader(line)
+		for _, candidate := range candidates {
+			if candidate.Detect(header) {
+				parser = candidate
+				break
+			}
+		}
+	}
+
+	result := &Result{Format: parser.Name()}
+	rows := 0
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Errors = append(result.Errors, RowError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if blank(fields) {
+			continue
+		}
+		if rows++; rows > MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		row := Row{Line: line, fields: make(map[string]string, len(header))}
+		for i, name := range header {
+			if i < len(fields) {
+				row.fields[name] = fields[i]
+			}
+		}
+		records, err := parser.Parse(row)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Line: line, Error: err.Error()})
+			continue
+		}
+		for _, record := range records {
+			record.Line = line
+			result.Records = append(result.Records, record)
+		}
+	}
+	return result, nil
+}
+
+func candidateParsers(format string, mapping *Mapping) ([]Parser, error) {
+	generic := newGenericParser(mapping)
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "", FormatAuto:
+		if mapping != nil {
+			return []Parser{generic}, nil
+		}
+		return append(append([]Parser{}, parsers...), generic), nil
+	case FormatGeneric:
+		return []Parser{generic}, nil
+	}
+	for _, p := range parsers {
+		if p.Name() == format {
+			return []Parser{p}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+func normalizeHeader(line []string) []string {
+	header := make([]string, len(line))
+	for i, name := range line {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return header
+}
+
+func hasColumns(header []string, names ...string) bool {
+	present := make(map[string]bool, len(header))
+	for _, name := range header {
+		present[name] = true
+	}
+	for _, name := range names {
+		if !present[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func blank(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
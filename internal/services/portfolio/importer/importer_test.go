@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func at(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+func txRecord(line int, kind models.TransactionType, symbol string, qty, price float64, quote string, when time.Time, id string) Record {
+	return Record{
+		Line:          line,
+		Kind:          KindTransaction,
+		Symbol:        symbol,
+		Type:          kind,
+		Quantity:      qty,
+		UnitPrice:     price,
+		QuoteCurrency: quote,
+		Timestamp:     when,
+		ExternalID:    id,
+	}
+}
+
+func sameRecord(got, want Record) bool {
+	return got.Line == want.Line && got.Kind == want.Kind && got.Symbol == want.Symbol &&
+		got.Type == want.Type && got.IncomeType == want.IncomeType &&
+		near(got.Quantity, want.Quantity) && near(got.UnitPrice, want.UnitPrice) &&
+		got.QuoteCurrency == want.QuoteCurrency && got.Timestamp.Equal(want.Timestamp) &&
+		got.ExternalID == want.ExternalID
+}
+
+func TestParse(t *testing.T) {
+	staking := txRecord(6, models.TransactionIncome, "ETH", 0.002, 2000, "usd", at(2024, 1, 6, 0, 0), "cb2")
+	staking.IncomeType = models.IncomeStaking
+	genericStaking := txRecord(3, models.TransactionIncome, "SOL", 0.5, 120, "usd", at(2024, 4, 2, 0, 0), "g2")
+	genericStaking.IncomeType = models.IncomeStaking
+
+	tests := []struct {
+		name       string
+		format     string
+		mapping    *Mapping
+		csv        string
+		wantFormat string
+		want       []Record
+		wantErrors []int
+	}{
+		{
+			name: "binance",
+			csv: `Date(UTC),Pair,Side,Price,Executed,Amount,Fee
+2024-03-01 10:00:00,ETHBTC,BUY,0.05,2ETH,0.1BTC,0.002ETH
+2024-03-02 11:00:00,BTCUSDT,SELL,60000,0.5BTC,"30,000USDT",10USDT
+2024-03-03 12:00:00,BTCTRY,BUY,2000000,0.01BTC,20000TRY,
+2024-03-04 13:00:00,BTCUSDT,HOLD,60000,0.5BTC,30000USDT,
+`,
+			wantFormat: "binance",
+			want: []Record{
+				// A coin quote moves both balances; the fee is charged in ETH
+				txRecord(2, models.TransactionBuy, "ETH", 2, 0.05, "btc", at(2024, 3, 1, 10, 0), ""),
+				txRecord(2, models.TransactionSell, "BTC", 0.1, 1, "btc", at(2024, 3, 1, 10, 0), ""),
+				{Line: 2, Kind: KindTransaction, Symbol: "ETH", Type: models.TransactionFee, Quantity: 0.002, QuoteCurrency: "usd", Timestamp: at(2024, 3, 1, 10, 0)},
+				// Stablecoin quote settles in dollars, net of the quote fee
+				txRecord(3, models.TransactionSell, "BTC", 0.5, 59980, "usd", at(2024, 3, 2, 11, 0), ""),
+				// Fiat quote is cash even when the app cannot price in it
+				txRecord(4, models.TransactionBuy, "BTC", 0.01, 2000000, "try", at(2024, 3, 3, 12, 0), ""),
+			},
+			wantErrors: []int{5},
+		},
+		{
+			name: "binance legacy",
+			csv: `Date(UTC),Market,Type,Price,Amount,Total,Fee,Fee Coin
+2021-05-01 08:00:00,BNBEUR,BUY,500,2,1000,1,EUR
+2021-05-02 08:00:00,ETHBNB,SELL,4,1,4,0.001,BNB
+`,
+			wantFormat: "binance",
+			want: []Record{
+				txRecord(2, models.TransactionBuy, "BNB", 2, 500.5, "eur", at(2021, 5, 1, 8, 0), ""),
+				txRecord(3, models.TransactionSell, "ETH", 1, 3.999, "bnb", at(2021, 5, 2, 8, 0), ""),
+				txRecord(3, models.TransactionBuy, "BNB", 3.999, 1, "bnb", at(2021, 5, 2, 8, 0), ""),
+			},
+		},
+		{
+			name: "coinbase",
+			csv: `Transactions
+User,someone@example.com
+
+ID,Timestamp,Transaction Type,Asset,Quantity Transacted,Price Currency,Price at Transaction,Subtotal,Total (inclusive of fees and/or spread),Fees and/or Spread,Notes
+cb1,2024-01-05 12:00:00 UTC,Buy,BTC,0.01,USD,"$40,000.00",$400.00,$405.00,$5.00,Bought 0.01 BTC
+cb2,2024-01-06T00:00:00Z,Staking Income,ETH,0.002,USD,2000,4,4,0,
+cb3,2024-01-07T00:00:00Z,Convert,ETH,0.5,USD,2000,1000,1010,10,Converted 0.5 ETH to 0.025 BTC
+cb4,2024-01-08T00:00:00Z,Deposit,USD,100,USD,1,100,100,0,
+cb5,2024-01-09T00:00:00Z,Send,BTC,-0.005,USD,42000,,,,
+cb6,2024-01-10T00:00:00Z,Mystery,BTC,1,USD,1,1,1,0,
+`,
+			wantFormat: "coinbase",
+			want: []Record{
+				// Blank lines are skipped by the reader, so the header is line 4
+				txRecord(5, models.TransactionBuy, "BTC", 0.01, 40500, "usd", at(2024, 1, 5, 12, 0), "cb1"),
+				staking,
+				txRecord(7, models.TransactionSell, "ETH", 0.5, 2000, "usd", at(2024, 1, 7, 0, 0), "cb3"),
+				txRecord(7, models.TransactionBuy, "BTC", 0.025, 40000, "usd", at(2024, 1, 7, 0, 0), "cb3/btc"),
+				txRecord(9, models.TransactionTransferOut, "BTC", 0.005, 42000, "usd", at(2024, 1, 9, 0, 0), "cb5"),
+			},
+			wantErrors: []int{10},
+		},
+		{
+			name: "kraken",
+			csv: `"txid","ordertxid","pair","time","type","ordertype","price","cost","fee","vol","margin","misc","ledgers"
+"T1","O1","XXBTZUSD","2024-02-01 09:30:00.25","buy","limit","50000","25000","40","0.5","0","",""
+"T2","O2","ETHXBT","2024-02-02 10:00:00","sell","market","0.05","0.1","0.0002","2","0","",""
+`,
+			wantFormat: "kraken",
+			want: []Record{
+				txRecord(2, models.TransactionBuy, "BTC", 0.5, 50080, "usd", at(2024, 2, 1, 9, 30).Add(250*time.Millisecond), "T1"),
+				txRecord(3, models.TransactionSell, "ETH", 2, 0.0499, "btc", at(2024, 2, 2, 10, 0), "T2"),
+				txRecord(3, models.TransactionBuy, "BTC", 0.0998, 1, "btc", at(2024, 2, 2, 10, 0), "T2/btc"),
+			},
+		},
+		{
+			name: "generic",
+			csv: `date,type,symbol,quantity,price,fee,quote,id
+2024-04-01,buy,SOL,10,100,5,USD,g1
+2024-04-02,staking,SOL,0.5,120,,,g2
+2024-04-03,,SOL,3,,,,
+2024-04-04,buy,SOL,1,,,,
+2024-04-05,lend,SOL,1,,,,
+`,
+			wantFormat: "generic",
+			want: []Record{
+				txRecord(2, models.TransactionBuy, "SOL", 10, 100.5, "usd", at(2024, 4, 1, 0, 0), "g1"),
+				genericStaking,
+				{Line: 4, Kind: KindHolding, Symbol: "SOL", Quantity: 3},
+			},
+			wantErrors: []int{5, 6},
+		},
+		{
+			name:   "generic with mapping",
+			format: FormatGeneric,
+			mapping: &Mapping{
+				Date:        "When",
+				Symbol:      "Coin",
+				Quantity:    "Units",
+				Total:       "Paid",
+				DateFormat:  "02.01.2006",
+				DefaultType: "buy",
+			},
+			csv: `When,Coin,Units,Paid
+15.03.2024,ADA,1000,500
+2024-03-16,ADA,1000,500
+`,
+			wantFormat: "generic",
+			want: []Record{
+				txRecord(2, models.TransactionBuy, "ADA", 1000, 0.5, "usd", at(2024, 3, 15, 0, 0), ""),
+			},
+			wantErrors: []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Parse(strings.NewReader(tt.csv), tt.format, tt.mapping)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if res.Format != tt.wantFormat {
+				t.Errorf("Parse() format = %q, want %q", res.Format, tt.wantFormat)
+			}
+			if len(res.Records) != len(tt.want) {
+				t.Fatalf("Parse() records = %+v, want %+v", res.Records, tt.want)
+			}
+			for i, want := range tt.want {
+				if !sameRecord(res.Records[i], want) {
+					t.Errorf("record %d = %+v, want %+v", i, res.Records[i], want)
+				}
+			}
+			if len(res.Errors) != len(tt.wantErrors) {
+				t.Fatalf("Parse() errors = %+v, want lines %v", res.Errors, tt.wantErrors)
+			}
+			for i, line := range tt.wantErrors {
+				if res.Errors[i].Line != line {
+					t.Errorf("error %d = %+v, want line %d", i, res.Errors[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		csv     string
+		wantErr error
+	}{
+		{name: "unknown format", format: "mtgox", csv: "symbol,quantity\nBTC,1\n", wantErr: ErrUnknownFormat},
+		{name: "unrecognized header", csv: "foo,bar\n1,2\n", wantErr: ErrUnrecognizedFormat},
+		// A Kraken header is not a Binance one
+		{name: "forced format mismatch", format: "binance", csv: "txid,pair,time,type,cost,vol\n", wantErr: ErrUnrecognizedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.csv), tt.format, nil); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"strings"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// krakenLegacyAssets are Kraken's four-letter asset codes carrying an X
+// (crypto) or Z (fiat) prefix
+var krakenLegacyAssets = map[string]string{
+	"XXBT": "BTC", "XETH": "ETH", "XLTC": "LTC", "XXRP": "XRP", "XXLM": "XLM",
+	"XXMR": "XMR", "XZEC": "ZEC", "XETC": "ETC", "XREP": "REP", "XMLN": "MLN",
+	"XXDG": "DOGE", "ZUSD": "USD", "ZEUR": "EUR", "ZGBP": "GBP", "ZCAD": "CAD",
+	"ZJPY": "JPY", "ZCHF": "CHF", "ZAUD": "AUD",
+}
+
+// krakenAliases are Kraken's names for assets listed elsewhere under
+// another ticker
+var krakenAliases = map[string]string{"XBT": "BTC", "XDG": "DOGE"}
+
+// krakenParser reads the Kraken trades export
+type krakenParser struct{}
+
+func (krakenParser) Name() string { return "kraken" }
+
+func (krakenParser) Detect(header []string) bool {
+	return hasColumns(header, "txid", "pair", "time", "type", "cost", "vol")
+}
+
+func (krakenParser) Parse(row Row) ([]Record, error) {
+	at, err := parseTime(row.Get("time"), "")
+	if err != nil {
+		return nil, err
+	}
+	side, err := parseSide(row.Get("type"))
+	if err != nil {
+		return nil, err
+	}
+	base, quote, err := splitKrakenPair(row.Get("pair"))
+	if err != nil {
+		return nil, err
+	}
+	qty, _, err := parseAmount(row.Get("vol"))
+	if err != nil {
+		return nil, err
+	}
+	total, _, err := parseAmount(row.Get("cost"))
+	if err != nil {
+		return nil, err
+	}
+	// Kraken charges fees in the quote currency
+	feeAmount, err := parseOptional(row.Get("fee"))
+	if err != nil {
+		return nil, err
+	}
+	if side == models.TransactionBuy {
+		total += feeAmount
+	} else {
+		total -= feeAmount
+	}
+	return trade(side, base, quote, qty, total, at, row.Get("txid"))
+}
+
+// splitKrakenPair splits pairs such as "XXBTZUSD", "XBTUSDT" or "XBT/USD"
+// and maps Kraken's asset codes to common tickers
+func splitKrakenPair(pair string) (string, string, error) {
+	pair = strings.ToUpper(strings.TrimSpace(pair))
+	if len(pair) == 8 && !strings.ContainsAny(pair, "/-_") {
+		if base, ok := krakenLegacyAssets[pair[:4]]; ok {
+			if quote, ok := krakenLegacyAssets[pair[4:]]; ok {
+				return base, quote, nil
+			}
+		}
+	}
+	base, quote, err := splitPair(strings.Replace(pair, "XBT", "BTC", 1))
+	if err != nil {
+		return "", "", err
+	}
+	return krakenAsset(base), krakenAsset(quote), nil
+}
+
+func krakenAsset(code string) string {
+	if legacy, ok := krakenLegacyAssets[code]; ok {
+		return legacy
+	}
+	if alias, ok := krakenAliases[code]; ok {
+		return alias
+	}
+	return code
+}
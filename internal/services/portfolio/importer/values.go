@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// amountPattern splits "0.5BTC", "BTC 0.5" or "1,200.00" into unit and number
+var amountPattern = regexp.MustCompile(`^([A-Za-z]+)?\s*([-+]?[0-9][0-9,]*(?:\.[0-9]*)?(?:[eE][-+]?[0-9]+)?|[-+]?\.[0-9]+)\s*([A-Za-z]+)?$`)
+
+// parseAmount reads a number that may carry a currency sign, thousands
+// separators and a leading or trailing asset code, returning the code
+// uppercased
+func parseAmount(value string) (float64, string, error) {
+	cleaned := strings.NewReplacer("$", "", "€", "", "£", "", "¥", "").Replace(strings.TrimSpace(value))
+	match := amountPattern.FindStringSubmatch(cleaned)
+	if match == nil {
+		return 0, "", fmt.Errorf("invalid number %q", value)
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", ""), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid number %q", value)
+	}
+	unit := match[1]
+	if unit == "" {
+		unit = match[3]
+	}
+	return number, strings.ToUpper(unit), nil
+}
+
+// parseOptional is parseAmount for columns that may be empty
+func parseOptional(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, _, err := parseAmount(value)
+	return number, err
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// parseTime reads a timestamp with layout, or when layout is empty with any
+// of the common export layouts or as Unix seconds or milliseconds. Times
+// without a zone are taken as UTC.
+func parseTime(value, layout string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, value, time.UTC)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+		}
+		return t.UTC(), nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if unix > 1e11 {
+			return time.UnixMilli(unix).UTC(), nil
+		}
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	if unix, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.UnixMilli(int64(unix * 1000)).UTC(), nil
+	}
+	for _, candidate := range timeLayouts {
+		if t, err := time.ParseInLocation(candidate, value, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+var fiatCurrencies = map[string]bool{
+	"usd": true, "eur": true, "gbp": true, "jpy": true, "aud": true, "cad": true,
+	"chf": true, "inr": true, "krw": true, "try": true, "brl": true, "sgd": true,
+	"hkd": true, "nzd": true, "zar": true, "mxn": true, "pln": true, "sek": true,
+	"nok": true, "dkk": true, "cny": true, "idr": true, "php": true, "thb": true,
+	"uah": true, "ngn": true, "aed": true, "ars": true,
+}
+
+// IsFiat reports whether code names a government currency, whether or not
+// the app can price in it
+func IsFiat(code string) bool {
+	return fiatCurrencies[strings.ToLower(strings.TrimSpace(code))]
+}
+
+// stablecoins are treated as the dollar they track when quoting a trade
+var stablecoins = map[string]bool{
+	"usdt": true, "usdc": true, "busd": true, "fdusd": true, "tusd": true,
+	"dai": true, "usdp": true, "pyusd": true,
+}
+
+// settlement maps a trade's quote asset to the currency its price is
+// recorded in, and reports whether the quote is cash. Fiat and stablecoin
+// quotes are cash and settle the trade; any other quote is a coin whose
+// balance the trade also moves.
+func settlement(quote string) (string, bool) {
+	code := strings.ToLower(quote)
+	if stablecoins[code] {
+		return "usd", true
+	}
+	return code, fiatCurrencies[code]
+}
+
+func parseSide(value string) (models.TransactionType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "buy", "b":
+		return models.TransactionBuy, nil
+	case "sell", "s":
+		return models.TransactionSell, nil
+	}
+	return "", fmt.Errorf("unsupported side %q", value)
+}
+
+// trade builds the records for buying or selling qty of base for total in
+// quote. A crypto quote adds the opposite leg on the quote coin, priced in
+// itself, so both balances move; the importing service restates both legs in
+// fiat before they are stored.
+func trade(side models.TransactionType, base, quote string, qty, total float64, at time.Time, id string) ([]Record, error) {
+	if qty <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	if total < 0 {
+		return nil, fmt.Errorf("total must not be negative")
+	}
+	if quote == "" {
+		return nil, fmt.Errorf("missing quote currency")
+	}
+	currency, cash := settlement(quote)
+	records := []Record{{
+		Kind:          KindTransaction,
+		Symbol:        base,
+		Type:          side,
+		Quantity:      qty,
+		UnitPrice:     total / qty,
+		QuoteCurrency: currency,
+		Timestamp:     at,
+		ExternalID:    id,
+	}}
+	if cash || total == 0 {
+		return records, nil
+	}
+
+	counter := models.TransactionSell
+	if side == models.TransactionSell {
+		counter = models.TransactionBuy
+	}
+	return append(records, Record{
+		Kind:          KindTransaction,
+		Symbol:        quote,
+		Type:          counter,
+		Quantity:      total,
+		UnitPrice:     1,
+		QuoteCurrency: currency,
+		Timestamp:     at,
+		ExternalID:    legID(id, strings.ToLower(quote)),
+	}), nil
+}
+
+// fee charges a fee paid in a coin. Fees paid in the quote currency are
+// folded into the trade price instead.
+func fee(asset string, amount float64, at time.Time, id string) Record {
+	return Record{
+		Kind:          KindTransaction,
+		Symbol:        asset,
+		Type:          models.TransactionFee,
+		Quantity:      amount,
+		QuoteCurrency: "usd",
+		Timestamp:     at,
+		ExternalID:    legID(id, "fee"),
+	}
+}
+
+// legID derives the external ID of a record split off an exchange entry
+func legID(id, leg string) string {
+	if id == "" {
+		return ""
+	}
+	return id + "/" + leg
+}
+
+// quoteAssets are the quote codes recognised at the end of a pair written
+// without a separator, longest first so USDT is not read as USD
+var quoteAssets = []string{
+	"FDUSD", "PYUSD", "USDT", "USDC", "BUSD", "TUSD", "USDP",
+	"BTC", "ETH", "BNB", "DAI", "USD", "EUR", "GBP", "TRY", "BRL",
+	"AUD", "CAD", "JPY", "CHF", "INR", "ZAR", "UAH", "NGN", "PLN",
+}
+
+// splitPair splits a trading pair such as "ETHBTC", "ETH/BTC" or "ETH-BTC"
+// into base and quote
+func splitPair(pair string) (string, string, error) {
+	pair = strings.ToUpper(strings.TrimSpace(pair))
+	if i := strings.IndexAny(pair, "/-_"); i >= 0 {
+		if i == 0 || i == len(pair)-1 {
+			return "", "", fmt.Errorf("unrecognized pair %q", pair)
+		}
+		return pair[:i], pair[i+1:], nil
+	}
+	for _, quote := range quoteAssets {
+		if len(pair) > len(quote) && strings.HasSuffix(pair, quote) {
+			return strings.TrimSuffix(pair, quote), quote, nil
+		}
+	}
+	return "", "", fmt.Errorf("unrecognized pair %q", pair)
+}
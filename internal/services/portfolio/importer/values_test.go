@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     float64
+		wantUnit string
+		wantErr  bool
+	}{
+		{value: "0.5", want: 0.5},
+		{value: " 1,200.00 ", want: 1200},
+		{value: "$40,000.50", want: 40000.5},
+		{value: "€-12.5", want: -12.5},
+		{value: ".25", want: 0.25},
+		{value: "1.5e-3", want: 0.0015},
+		{value: "0.5BTC", want: 0.5, wantUnit: "BTC"},
+		{value: "30,000USDT", want: 30000, wantUnit: "USDT"},
+		{value: "eth 2", want: 2, wantUnit: "ETH"},
+		{value: "", wantErr: true},
+		{value: "n/a", wantErr: true},
+		{value: "1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, unit, err := parseAmount(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAmount(%q) = %v %q, want an error", tt.value, got, unit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAmount(%q) error = %v", tt.value, err)
+			}
+			if !near(got, tt.want) || unit != tt.wantUnit {
+				t.Errorf("parseAmount(%q) = %v %q, want %v %q", tt.value, got, unit, tt.want, tt.wantUnit)
+			}
+		})
+	}
+}
+
+func TestSplitPair(t *testing.T) {
+	tests := []struct {
+		pair      string
+		wantBase  string
+		wantQuote string
+		wantErr   bool
+	}{
+		{pair: "ETH/BTC", wantBase: "ETH", wantQuote: "BTC"},
+		{pair: "eth-usd", wantBase: "ETH", wantQuote: "USD"},
+		{pair: "SOL_EUR", wantBase: "SOL", wantQuote: "EUR"},
+		{pair: "ETHBTC", wantBase: "ETH", wantQuote: "BTC"},
+		// Longest quote first, so not BTCUS and DT
+		{pair: "BTCUSDT", wantBase: "BTC", wantQuote: "USDT"},
+		{pair: "BTCFDUSD", wantBase: "BTC", wantQuote: "FDUSD"},
+		{pair: "BTCTRY", wantBase: "BTC", wantQuote: "TRY"},
+		{pair: "USDT", wantErr: true},
+		{pair: "ETHXYZ", wantErr: true},
+		{pair: "/BTC", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pair, func(t *testing.T) {
+			base, quote, err := splitPair(tt.pair)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("splitPair(%q) = %q, %q, want an error", tt.pair, base, quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitPair(%q) error = %v", tt.pair, err)
+			}
+			if base != tt.wantBase || quote != tt.wantQuote {
+				t.Errorf("splitPair(%q) = %q, %q, want %q, %q", tt.pair, base, quote, tt.wantBase, tt.wantQuote)
+			}
+		})
+	}
+}
+
+func TestSplitKrakenPair(t *testing.T) {
+	tests := []struct {
+		pair      string
+		wantBase  string
+		wantQuote string
+	}{
+		{pair: "XXBTZUSD", wantBase: "BTC", wantQuote: "USD"},
+		{pair: "XETHZEUR", wantBase: "ETH", wantQuote: "EUR"},
+		{pair: "XBTUSDT", wantBase: "BTC", wantQuote: "USDT"},
+		{pair: "ETHXBT", wantBase: "ETH", wantQuote: "BTC"},
+		{pair: "XDG/USD", wantBase: "DOGE", wantQuote: "USD"},
+		{pair: "SOLUSD", wantBase: "SOL", wantQuote: "USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pair, func(t *testing.T) {
+			base, quote, err := splitKrakenPair(tt.pair)
+			if err != nil {
+				t.Fatalf("splitKrakenPair(%q) error = %v", tt.pair, err)
+			}
+			if base != tt.wantBase || quote != tt.wantQuote {
+				t.Errorf("splitKrakenPair(%q) = %q, %q, want %q, %q", tt.pair, base, quote, tt.wantBase, tt.wantQuote)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	noon := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		layout  string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-01T12:00:00Z", want: noon},
+		{value: "2024-03-01T14:00:00+02:00", want: noon},
+		{value: "2024-03-01 12:00:00", want: noon},
+		{value: "2024-03-01 12:00:00 UTC", want: noon},
+		{value: "2024-03-01 12:00:00.250", want: noon.Add(250 * time.Millisecond)},
+		{value: "03/01/2024 12:00", want: noon},
+		{value: "2024/03/01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "1709294400", want: noon},
+		{value: "1709294400000", want: noon},
+		{value: "1709294400.5", want: noon.Add(500 * time.Millisecond)},
+		{value: "01.03.2024", layout: "02.01.2006", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-01", layout: "02.01.2006", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTime(tt.value, tt.layout)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTime(%q, %q) = %v, want an error", tt.value, tt.layout, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTime(%q, %q) error = %v", tt.value, tt.layout, err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parseTime(%q, %q) = %v, want %v", tt.value, tt.layout, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/returns"
+)
+
+// PeriodReturn reports returns over one standard period. Rates are
+// fractions (0.05 is 5%). MWR is the money-weighted return earned over the
+// period and XIRR the same rate annualized.
+type PeriodReturn struct {
+	Period     string    `json:"period"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	StartValue float64   `json:"startValue"`
+	EndValue   float64   `json:"endValue"`
+	NetFlows   float64   `json:"netFlows"` // deposits minus withdrawals
+	TWR        *float64  `json:"twr"`
+	MWR        *float64  `json:"mwr"`
+	XIRR       *float64  `json:"xirr"`
+	// Unavailable explains why no returns could be computed
+	Unavailable string `json:"unavailable,omitempty"`
+}
+
+type Performance struct {
+	Currency string         `json:"currency"`
+	Periods  []PeriodReturn `json:"periods"`
+}
+
+// errNoPrice marks a valuation that lacks a price for a held coin
+var errNoPrice = errors.New("no price")
+
+// Performance computes time-weighted and money-weighted returns for the
+// standard periods. Buys and transfers in count as deposits, sells and
+// transfers out as withdrawals; fees are losses. Past valuations come from
+// the local price history store, so periods before its coverage are reported
+// as unavailable until backfilled. Manually entered holdings have no dates and
+// are treated as held throughout.
+func (s *Service) Performance(ctx context.Context, userID string, portfolioID string, currency string) (*Performance, error) {
+	manual, err := s.repo.ListHoldings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.convertLedger(transactions, currency)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := s.marketService.ConversionRate("usd", currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	v := &valuer{s: s, manual: withoutLedgerCoins(manual, transactions), ledger: ledger, currency: currency, rate: rate, now: now,
+		cache: make(map[valuationKey]float64)}
+
+	periods := []struct {
+		name  string
+		start time.Time
+	}{
+		{"1D", now.AddDate(0, 0, -1)},
+		{"7D", now.AddDate(0, 0, -7)},
+		{"30D", now.AddDate(0, 0, -30)},
+		{"YTD", time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"1Y", now.AddDate(-1, 0, 0)},
+	}
+	if len(ledger) > 0 {
+		// Start just before the first entry so it counts as a flow
+		periods = append(periods, struct {
+			name  string
+			start time.Time
+		}{"ALL", ledger[0].Timestamp.Time().UTC().Add(-time.Millisecond)})
+	}
+
+	perf := &Performance{Currency: currency}
+	for _, p := range periods {
+		result, err := v.period(ctx, p.name, p.start)
+		if err != nil {
+			return nil, err
+		}
+		perf.Periods = append(perf.Periods, result)
+	}
+	return perf, nil
+}
+
+type valuationKey struct {
+	at        time.Time
+	inclusive bool
+}
+
+// valuer values a ledger at arbitrary instants, memoizing results so periods
+// that share flows do not repeat price lookups
+type valuer struct {
+	s        *Service
+	manual   []models.Holding
+	ledger   []models.Transaction // converted into currency, time ordered
+	currency string
+	rate     float64 // USD to currency, for stored prices
+	now      time.Time
+	cache    map[valuationKey]float64
+}
+
+func (v *valuer) period(ctx context.Context, name string, start time.Time) (PeriodReturn, error) {
+	result := PeriodReturn{Period: name, From: start, To: v.now}
+	unavailable := func(err error) (PeriodReturn, error) {
+		if errors.Is(err, errNoPrice) {
+			result.Unavailable = err.Error()
+			return result, nil
+		}
+		return result, err
+	}
+
+	startValue, err := v.valueAt(ctx, start, true)
+	if err != nil {
+		return unavailable(err)
+	}
+	result.StartValue = startValue
+
+	var steps []returns.Step
+	var flows []returns.CashFlow
+	if startValue > 0 {
+		flows = append(flows, returns.CashFlow{At: start, Amount: -startValue})
+	}
+	for i := 0; i < len(v.ledger); {
+		// Entries sharing a timestamp form one flow
+		j := i + 1
+		for j < len(v.ledger) && v.ledger[j].Timestamp == v.ledger[i].Timestamp {
+			j++
+		}
+		group := v.ledger[i:j]
+		i = j
+		at := group[0].Timestamp.Time().UTC()
+		if !at.After(start) || at.After(v.now) {
+			continue
+		}
+		var net float64
+		for _, tx := range group {
+			flow, err := v.flowValue(ctx, tx)
+			if err != nil {
+				return unavailable(err)
+			}
+			net += flow
+		}
+		if net == 0 {
+			continue
+		}
+		before, err := v.valueAt(ctx, at, false)
+		if err != nil {
+			return unavailable(err)
+		}
+		after, err := v.valueAt(ctx, at, true)
+		if err != nil {
+			return unavailable(err)
+		}
+		steps = append(steps, returns.Step{At: at, Before: before, After: after})
+		flows = append(flows, returns.CashFlow{At: at, Amount: -net})
+		result.NetFlows += net
+	}
+
+	endValue, err := v.valueAt(ctx, v.now, true)
+	if err != nil {
+		return unavailable(err)
+	}
+	result.EndValue = endValue
+	flows = append(flows, returns.CashFlow{At: v.now, Amount: endValue})
+
+	if twr, ok := returns.TimeWeighted(startValue, steps, endValue); ok {
+		result.TWR = &twr
+	}
+	if xirr, err := returns.XIRR(flows); err == nil {
+		mwr := returns.Deannualize(xirr, v.now.Sub(start))
+		result.XIRR = &xirr
+		result.MWR = &mwr
+	}
+	return result, nil
+}
+
+// flowValue is the value a ledger entry moves into (positive) or out of
+// (negative) the portfolio. Transfers without a unit price are valued at
+// the market price of the time.
+func (v *valuer) flowValue(ctx context.Context, tx models.Transaction) (float64, error) {
+	sign := 1.0
+	switch tx.Type {
+	case models.TransactionBuy, models.TransactionTransferIn:
+	case models.TransactionSell, models.TransactionTransferOut:
+		sign = -1
+	default:
+		return 0, nil
+	}
+	price := tx.UnitPrice
+	if price == 0 {
+		prices, err := v.prices(ctx, []string{tx.CoinID}, tx.Timestamp.Time())
+		if err != nil {
+			return 0, err
+		}
+		var ok bool
+		if price, ok = prices[tx.CoinID]; !ok {
+			return 0, fmt.Errorf("%w for %s at %s", errNoPrice, tx.CoinID, tx.Timestamp.Time().UTC().Format(time.RFC3339))
+		}
+	}
+	return sign * tx.Quantity * price, nil
+}
+
+// valueAt values the holdings at t, including ledger entries stamped exactly
+// at t only when inclusive is set
+func (v *valuer) valueAt(ctx context.Context, t time.Time, inclusive bool) (float64, error) {
+	key := valuationKey{at: t, inclusive: inclusive}
+	if value, ok := v.cache[key]; ok {
+		return value, nil
+	}
+
+	cutoff := models.ToPrimitiveDateTime(t)
+	balances := make(coinBalances)
+	for _, h := range v.manual {
+		balances.add(h.CoinID, h.Amount)
+	}
+	for _, tx := range v.ledger {
+		if tx.Timestamp > cutoff || (tx.Timestamp == cutoff && !inclusive) {
+			break
+		}
+		balances.add(tx.CoinID, tx.QuantityDelta())
+	}
+	var ids []string
+	for coinID, b := range balances {
+		if b.held() {
+			ids = append(ids, coinID)
+		}
+	}
+	prices, err := v.prices(ctx, ids, t)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, coinID := range ids {
+		price, ok := prices[coinID]
+		if !ok {
+			return 0, fmt.Errorf("%w for %s at %s", errNoPrice, coinID, t.UTC().Format(time.RFC3339))
+		}
+		total += balances[coinID].amount * price
+	}
+	v.cache[key] = total
+	return total, nil
+}
+
+// prices uses live market prices for the present and the price history
+// store for the past
+func (v *valuer) prices(ctx context.Context, ids []string, t time.Time) (map[string]float64, error) {
+	if len(ids) == 0 {
+		return map[string]float64{}, nil
+	}
+	if !t.Before(v.now) {
+		return v.s.marketService.GetPrices(ids, v.currency)
+	}
+	prices, err := v.s.priceHistory.PricesAt(ctx, ids, t)
+	if err != nil {
+		return nil, err
+	}
+	for id := range prices {
+		prices[id] *= v.rate
+	}
+	return prices, nil
+}
@@ -0,0 +1,172 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+)
+
+var (
+	ErrDefaultPortfolio  = errors.New("the default portfolio cannot be deleted")
+	ErrPortfolioNotEmpty = errors.New("portfolio still has holdings or transactions")
+)
+
+// ListPortfolios returns the user's default portfolio followed by their named
+// portfolios in creation order
+func (s *Service) ListPortfolios(ctx context.Context, userID string) ([]models.Portfolio, error) {
+	stored, err := s.repo.ListPortfolios(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	portfolios := []models.Portfolio{defaultPortfolio(userID)}
+	for _, p := range stored {
+		if p.ID == models.DefaultPortfolioID {
+			portfolios[0] = p
+			continue
+		}
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, nil
+}
+
+// GetPortfolio returns one of the user's portfolios. The default portfolio
+// always exists, even before it has been renamed or configured.
+func (s *Service) GetPortfolio(ctx context.Context, userID string, id string) (*models.Portfolio, error) {
+	portfolio, err := s.repo.GetPortfolio(ctx, userID, id)
+	if errors.Is(err, ErrNotFound) && id == models.DefaultPortfolioID {
+		p := defaultPortfolio(userID)
+		return &p, nil
+	}
+	return portfolio, err
+}
+
+func defaultPortfolio(userID string) models.Portfolio {
+	return models.Portfolio{ID: models.DefaultPortfolioID, UserID: userID, Name: "Default"}
+}
+
+func (s *Service) CreatePortfolio(ctx context.Context, portfolio models.Portfolio) (*models.Portfolio, error) {
+	portfolio.ID = primitive.NewObjectID().Hex()
+	portfolio.CreatedAt = models.ToPrimitiveDateTime(time.Now())
+	if err := s.validatePortfolio(&portfolio); err != nil {
+		return nil, err
+	}
+	return s.repo.SavePortfolio(ctx, portfolio)
+}
+
+func (s *Service) UpdatePortfolio(ctx context.Context, portfolio models.Portfolio) (*models.Portfolio, error) {
+	existing, err := s.GetPortfolio(ctx, portfolio.UserID, portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+	portfolio.CreatedAt = existing.CreatedAt
+	portfolio.Targets = existing.Targets
+	portfolio.DriftTolerancePercent = existing.DriftTolerancePercent
+	if err := s.validatePortfolio(&portfolio); err != nil {
+		return nil, err
+	}
+	return s.repo.SavePortfolio(ctx, portfolio)
+}
+
+// DeletePortfolio removes an empty named portfolio and its snapshots
+func (s *Service) DeletePortfolio(ctx context.Context, userID string, id string) error {
+	if id == models.DefaultPortfolioID {
+		return ErrDefaultPortfolio
+	}
+	if _, err := s.repo.GetPortfolio(ctx, userID, id); err != nil {
+		return err
+	}
+	holdings, err := s.repo.ListHoldings(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	transactions, err := s.repo.ListTransactions(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if len(holdings) > 0 || len(transactions) > 0 {
+		return ErrPortfolioNotEmpty
+	}
+	if err := s.repo.DeletePortfolio(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSnapshots(ctx, userID, id)
+}
+
+func (s *Service) validatePortfolio(portfolio *models.Portfolio) error {
+	portfolio.Name = strings.TrimSpace(portfolio.Name)
+	if portfolio.UserID == "" || portfolio.Name == "" {
+		return errors.New("invalid portfolio payload")
+	}
+	if portfolio.CostBasisMethod != "" && !costbasis.Method(portfolio.CostBasisMethod).Valid() {
+		return errors.New("invalid cost basis method")
+	}
+	if portfolio.BaseCurrency != "" {
+		currency, err := s.marketService.NormalizeCurrency(portfolio.BaseCurrency)
+		if err != nil {
+			return err
+		}
+		portfolio.BaseCurrency = currency
+	}
+	return nil
+}
+
+// PortfolioValue is one portfolio's valuation within an aggregate view
+type PortfolioValue struct {
+	Portfolio  models.Portfolio   `json:"portfolio"`
+	TotalValue float64            `json:"totalValue"`
+	Holdings   []HoldingWithValue `json:"holdings"`
+}
+
+// AssetTotal is a coin's combined position across portfolios
+type AssetTotal struct {
+	CoinID string  `json:"coinId"`
+	Amount float64 `json:"amount"`
+	Value  float64 `json:"value"`
+}
+
+type Aggregate struct {
+	Currency   string           `json:"currency"`
+	TotalValue float64          `json:"totalValue"`
+	Portfolios []PortfolioValue `json:"portfolios"`
+	Assets     []AssetTotal     `json:"assets"`
+}
+
+// GetAggregate values every portfolio of the user in a single currency and
+// combines their positions per coin. Each portfolio keeps its own cost basis.
+func (s *Service) GetAggregate(ctx context.Context, userID string, currency string) (*Aggregate, error) {
+	portfolios, err := s.ListPortfolios(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	agg := &Aggregate{Currency: currency}
+	assets := make(map[string]*AssetTotal)
+	var order []string
+	for _, p := range portfolios {
+		holdings, total, err := s.GetHoldingsWithValue(ctx, userID, p.ID, currency)
+		if err != nil {
+			return nil, err
+		}
+		agg.Portfolios = append(agg.Portfolios, PortfolioValue{Portfolio: p, TotalValue: total, Holdings: holdings})
+		agg.TotalValue += total
+		for _, h := range holdings {
+			asset, ok := assets[h.CoinID]
+			if !ok {
+				asset = &AssetTotal{CoinID: h.CoinID}
+				assets[h.CoinID] = asset
+				order = append(order, h.CoinID)
+			}
+			asset.Amount += h.Amount
+			asset.Value += h.CurrentValue
+		}
+	}
+	for _, coinID := range order {
+		agg.Assets = append(agg.Assets, *assets[coinID])
+	}
+	return agg, nil
+}
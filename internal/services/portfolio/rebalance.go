@@ -0,0 +1,234 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// defaultDriftTolerance applies when targets are saved without a band
+const defaultDriftTolerance = 5.0
+
+// weightEpsilon absorbs rounding when target weights are summed
+const weightEpsilon = 0.01
+
+var (
+	ErrNoTargets          = errors.New("portfolio has no target allocation")
+	ErrNothingToRebalance = errors.New("portfolio has no priced holdings to rebalance")
+)
+
+// SetTargets replaces a portfolio's target allocation. Weights are percent
+// and must add up to 100. Coin IDs are lower-cased like holdings, and coins
+// not already targeted must be in the coin catalog.
+func (s *Service) SetTargets(ctx context.Context, userID string, portfolioID string, targets []models.TargetWeight, tolerance float64) (*models.Portfolio, error) {
+	portfolio, err := s.GetPortfolio(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if tolerance == 0 {
+		tolerance = defaultDriftTolerance
+	}
+	if tolerance < 0 || tolerance > 100 {
+		return nil, errors.New("drift tolerance must be between 0 and 100")
+	}
+
+	previous := make(map[string]bool, len(portfolio.Targets))
+	for _, t := range portfolio.Targets {
+		previous[t.CoinID] = true
+	}
+	seen := make(map[string]struct{}, len(targets))
+	var sum float64
+	for i := range targets {
+		t := &targets[i]
+		t.CoinID = strings.ToLower(strings.TrimSpace(t.CoinID))
+		if t.CoinID == "" || t.Weight < 0 || t.Weight > 100 || t.TolerancePercent < 0 {
+			return nil, errors.New("invalid target payload")
+		}
+		if _, dup := seen[t.CoinID]; dup {
+			return nil, fmt.Errorf("duplicate target for %s", t.CoinID)
+		}
+		if !previous[t.CoinID] {
+			if err := s.catalog.Validate(t.CoinID); err != nil {
+				return nil, err
+			}
+		}
+		seen[t.CoinID] = struct{}{}
+		sum += t.Weight
+	}
+	if len(targets) > 0 && math.Abs(sum-100) > weightEpsilon {
+		return nil, fmt.Errorf("target weights add up to %.2f, not 100", sum)
+	}
+
+	portfolio.Targets = targets
+	portfolio.DriftTolerancePercent = tolerance
+	if len(targets) == 0 {
+		portfolio.DriftTolerancePercent = 0
+	}
+	return s.repo.SavePortfolio(ctx, *portfolio)
+}
+
+// AllocationRow compares one coin's current weight with its target. Weights
+// and drift are in percent.
+type AllocationRow struct {
+	CoinID        string  `json:"coinId"`
+	Amount        float64 `json:"amount"`
+	Price         float64 `json:"price"`
+	Value         float64 `json:"value"`
+	CurrentWeight float64 `json:"currentWeight"`
+	TargetWeight  float64 `json:"targetWeight"`
+	Drift         float64 `json:"drift"`
+	Tolerance     float64 `json:"tolerance"`
+	InBand        bool    `json:"inBand"`
+}
+
+type Trade struct {
+	CoinID       string  `json:"coinId"`
+	Side         string  `json:"side"` // buy or sell
+	Value        float64 `json:"value"`
+	Amount       float64 `json:"amount"`
+	EstimatedFee float64 `json:"estimatedFee"`
+}
+
+type RebalancePlan struct {
+	Currency    string          `json:"currency"`
+	TotalValue  float64         `json:"totalValue"`
+	InBand      bool            `json:"inBand"`
+	Allocations []AllocationRow `json:"allocations"`
+	Trades      []Trade         `json:"trades"`
+	// Skipped trades fell below the minimum trade size or buy a coin no
+	// provider can price
+	Skipped       []Trade `json:"skipped,omitempty"`
+	EstimatedFees float64 `json:"estimatedFees"`
+	// NetCash is sell proceeds minus buy costs and fees; negative means the
+	// plan needs fresh cash
+	NetCash float64 `json:"netCash"`
+	// Unpriced coins cannot be valued and are left out of the plan
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Rebalance plans the trades that bring out-of-band coins back to target.
+// Their net proceeds or cost is spread over the in-band coins by target
+// weight, so the plan is roughly self-funding. Held coins without a target
+// have a target of zero. minTrade is in currency; feePercent is charged on
+// every trade's value.
+func (s *Service) Rebalance(ctx context.Context, userID string, portfolioID string, currency string, minTrade, feePercent float64) (*RebalancePlan, error) {
+	portfolio, err := s.GetPortfolio(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(portfolio.Targets) == 0 {
+		return nil, ErrNoTargets
+	}
+	holdings, _, err := s.GetHoldingsWithValue(ctx, userID, portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RebalancePlan{Currency: currency, InBand: true}
+	rows := make(map[string]*AllocationRow)
+	var order []string
+	row := func(coinID string) *AllocationRow {
+		r, ok := rows[coinID]
+		if !ok {
+			r = &AllocationRow{CoinID: coinID, Tolerance: portfolio.DriftTolerancePercent}
+			rows[coinID] = r
+			order = append(order, coinID)
+		}
+		return r
+	}
+	for _, h := range holdings {
+		if h.PriceUnavailable {
+			plan.Unpriced = append(plan.Unpriced, h.CoinID)
+			continue
+		}
+		r := row(h.CoinID)
+		r.Amount += h.Amount
+		r.Price = h.CurrentPrice
+		r.Value += h.CurrentValue
+		plan.TotalValue += h.CurrentValue
+	}
+	for _, t := range portfolio.Targets {
+		r := row(t.CoinID)
+		r.TargetWeight = t.Weight
+		if t.TolerancePercent > 0 {
+			r.Tolerance = t.TolerancePercent
+		}
+	}
+	if plan.TotalValue <= 0 {
+		return nil, ErrNothingToRebalance
+	}
+
+	// Coins that are targeted but not yet held still need a price to buy
+	var missing []string
+	for _, coinID := range order {
+		if rows[coinID].Price == 0 {
+			missing = append(missing, coinID)
+		}
+	}
+	if len(missing) > 0 {
+		prices, err := s.marketService.GetPrices(missing, currency)
+		if err != nil {
+			return nil, err
+		}
+		for _, coinID := range missing {
+			rows[coinID].Price = prices[coinID]
+		}
+	}
+
+	deltas := make(map[string]float64)
+	var net, inBandWeight float64
+	for _, coinID := range order {
+		r := rows[coinID]
+		r.CurrentWeight = r.Value / plan.TotalValue * 100
+		r.Drift = r.CurrentWeight - r.TargetWeight
+		r.InBand = math.Abs(r.Drift) <= r.Tolerance
+		if r.InBand {
+			inBandWeight += r.TargetWeight
+			continue
+		}
+		plan.InBand = false
+		deltas[coinID] = r.TargetWeight/100*plan.TotalValue - r.Value
+		net += deltas[coinID]
+	}
+	if !plan.InBand && inBandWeight > 0 {
+		for _, coinID := range order {
+			if r := rows[coinID]; r.InBand {
+				deltas[coinID] = -net * r.TargetWeight / inBandWeight
+			}
+		}
+	}
+
+	for _, coinID := range order {
+		r := rows[coinID]
+		plan.Allocations = append(plan.Allocations, *r)
+		delta := deltas[coinID]
+		if delta == 0 {
+			continue
+		}
+		trade := Trade{CoinID: coinID, Side: "buy", Value: math.Abs(delta)}
+		if delta < 0 {
+			trade.Side = "sell"
+		}
+		if r.Price > 0 {
+			trade.Amount = trade.Value / r.Price
+		}
+		trade.EstimatedFee = trade.Value * feePercent / 100
+		if trade.Value < minTrade || (trade.Side == "buy" && r.Price == 0) {
+			plan.Skipped = append(plan.Skipped, trade)
+			continue
+		}
+		plan.Trades = append(plan.Trades, trade)
+		plan.EstimatedFees += trade.EstimatedFee
+		if trade.Side == "sell" {
+			plan.NetCash += trade.Value
+		} else {
+			plan.NetCash -= trade.Value
+		}
+	}
+	plan.NetCash -= plan.EstimatedFees
+	return plan, nil
+}
@@ -0,0 +1,130 @@
+// Package returns computes time-weighted and money-weighted portfolio
+// returns from valuations and external cash flows.
+package returns
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrNoSolution = errors.New("no internal rate of return for these cash flows")
+
+// Step is the portfolio's market value just before and just after the
+// external flows at At
+type Step struct {
+	At     time.Time
+	Before float64
+	After  float64
+}
+
+// TimeWeighted chains the returns of the sub-periods delimited by steps, so
+// deposits and withdrawals do not count as performance. ok is false when the
+// portfolio held no value in any sub-period.
+func TimeWeighted(startValue float64, steps []Step, endValue float64) (twr float64, ok bool) {
+	growth := 1.0
+	prev := startValue
+	for _, step := range steps {
+		if prev > 0 {
+			growth *= step.Before / prev
+			ok = true
+		}
+		prev = step.After
+	}
+	if prev > 0 {
+		growth *= endValue / prev
+		ok = true
+	}
+	return growth - 1, ok
+}
+
+// CashFlow is money moving between the investor and the portfolio: negative
+// when the investor pays in, positive when they take out
+type CashFlow struct {
+	At     time.Time
+	Amount float64
+}
+
+const (
+	xirrTolerance  = 1e-9
+	xirrIterations = 100
+	yearDays       = 365.0
+)
+
+// XIRR returns the annualized rate at which the flows' net present value is
+// zero. Newton's method is tried first, falling back to bisection.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoSolution
+	}
+	var hasIn, hasOut bool
+	origin := flows[0].At
+	for _, f := range flows {
+		hasIn = hasIn || f.Amount < 0
+		hasOut = hasOut || f.Amount > 0
+		if f.At.Before(origin) {
+			origin = f.At
+		}
+	}
+	if !hasIn || !hasOut {
+		return 0, ErrNoSolution
+	}
+
+	npv := func(rate float64) (value, derivative float64) {
+		for _, f := range flows {
+			years := f.At.Sub(origin).Hours() / 24 / yearDays
+			factor := math.Pow(1+rate, years)
+			value += f.Amount / factor
+			derivative -= years * f.Amount / (factor * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for i := 0; i < xirrIterations; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < xirrTolerance {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < xirrTolerance {
+			return next, nil
+		}
+		rate = next
+	}
+
+	low, high := -0.999999, 1.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	for i := 0; lowValue*highValue > 0 && i < 60; i++ {
+		high *= 2
+		highValue, _ = npv(high)
+	}
+	if lowValue*highValue > 0 {
+		return 0, ErrNoSolution
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < xirrTolerance || high-low < xirrTolerance {
+			return mid, nil
+		}
+		if midValue*lowValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+// Deannualize converts an annual rate into the rate earned over d
+func Deannualize(annual float64, d time.Duration) float64 {
+	return math.Pow(1+annual, d.Hours()/24/yearDays) - 1
+}
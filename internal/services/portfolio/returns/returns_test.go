@@ -0,0 +1,174 @@
+package returns
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name    string
+		flows   []CashFlow
+		want    float64
+		wantErr error
+	}{
+		{
+			name: "one year at ten percent",
+			flows: []CashFlow{
+				{At: date(2023, 1, 1), Amount: -1000},
+				{At: date(2024, 1, 1), Amount: 1100},
+			},
+			want: 0.1,
+		},
+		{
+			// Known result of the spreadsheet XIRR reference example
+			name: "irregular flows",
+			flows: []CashFlow{
+				{At: date(2008, 1, 1), Amount: -10000},
+				{At: date(2008, 3, 1), Amount: 2750},
+				{At: date(2008, 10, 30), Amount: 4250},
+				{At: date(2009, 2, 15), Amount: 3250},
+				{At: date(2009, 4, 1), Amount: 2750},
+			},
+			want: 0.373362535,
+		},
+		{
+			name: "losing money",
+			flows: []CashFlow{
+				{At: date(2021, 1, 1), Amount: -1000},
+				{At: date(2022, 1, 1), Amount: 500},
+			},
+			want: -0.5,
+		},
+		{
+			name:    "single flow",
+			flows:   []CashFlow{{At: date(2021, 1, 1), Amount: -1000}},
+			wantErr: ErrNoSolution,
+		},
+		{
+			name: "only deposits",
+			flows: []CashFlow{
+				{At: date(2021, 1, 1), Amount: -1000},
+				{At: date(2022, 1, 1), Amount: -500},
+			},
+			wantErr: ErrNoSolution,
+		},
+		{
+			// Net present value is negative at every rate, so neither
+			// Newton's method nor bisection can converge
+			name: "no root",
+			flows: []CashFlow{
+				{At: date(2021, 1, 1), Amount: -1000},
+				{At: date(2021, 7, 1), Amount: 100},
+				{At: date(2022, 1, 1), Amount: -1000},
+			},
+			wantErr: ErrNoSolution,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := XIRR(tt.flows)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("XIRR() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("XIRR() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("XIRR() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeWeighted(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  float64
+		steps  []Step
+		end    float64
+		want   float64
+		wantOK bool
+	}{
+		{
+			name:   "no flows",
+			start:  100,
+			end:    120,
+			want:   0.2,
+			wantOK: true,
+		},
+		{
+			// A deposit of 100 doubles the value without counting as return
+			name:   "deposit between two ten percent periods",
+			start:  100,
+			steps:  []Step{{At: date(2024, 6, 1), Before: 110, After: 210}},
+			end:    231,
+			want:   0.21,
+			wantOK: true,
+		},
+		{
+			name:   "first deposit",
+			start:  0,
+			steps:  []Step{{At: date(2024, 6, 1), Before: 0, After: 100}},
+			end:    90,
+			want:   -0.1,
+			wantOK: true,
+		},
+		{
+			name:   "full withdrawal",
+			start:  100,
+			steps:  []Step{{At: date(2024, 6, 1), Before: 150, After: 0}},
+			end:    0,
+			want:   0.5,
+			wantOK: true,
+		},
+		{
+			name:  "never held value",
+			start: 0,
+			end:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TimeWeighted(tt.start, tt.steps, tt.end)
+			if ok != tt.wantOK {
+				t.Fatalf("TimeWeighted() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("TimeWeighted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeannualize(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name   string
+		annual float64
+		d      time.Duration
+		want   float64
+	}{
+		{name: "one year", annual: 0.1, d: 365 * day, want: 0.1},
+		{name: "half a year", annual: 0.21, d: 365 * day / 2, want: 0.1},
+		{name: "two years", annual: 0.1, d: 730 * day, want: 0.21},
+		{name: "zero duration", annual: 0.5, d: 0, want: 0},
+		{name: "negative rate", annual: -0.19, d: 365 * day / 2, want: -0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Deannualize(tt.annual, tt.d); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Deannualize(%v, %v) = %v, want %v", tt.annual, tt.d, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/services/market"
+	"github.com/faisal/crypto/backend/internal/services/risk"
+)
+
+type AssetRisk struct {
+	CoinID string  `json:"coinId"`
+	Weight float64 `json:"weight"` // share of the portfolio's latest value
+	risk.Metrics
+}
+
+// PortfolioRisk reports metrics for the portfolio as a whole and for each
+// held coin, plus the correlation of daily returns between every pair of
+// held coins
+type PortfolioRisk struct {
+	Currency     string                        `json:"currency"`
+	Range        string                        `json:"range"`
+	Benchmark    string                        `json:"benchmark"`
+	RiskFreeRate float64                       `json:"riskFreeRate"`
+	Portfolio    risk.Metrics                  `json:"portfolio"`
+	Assets       []AssetRisk                   `json:"assets"`
+	Correlations map[string]map[string]float64 `json:"correlations"`
+	// Unavailable lists coins left out because no price history was found
+	// or it covered too little of the range
+	Unavailable map[string]string `json:"unavailable,omitempty"`
+}
+
+// minRiskCoverage is the share of the range a coin's history must cover to
+// be included. Aligning a shorter series would cut every other series down
+// to its window.
+const minRiskCoverage = 0.5
+
+// Risk values today's holdings over the daily price history of rng, so the
+// portfolio series shows how the current allocation would have behaved
+// rather than replaying past trades
+func (s *Service) Risk(ctx context.Context, userID string, portfolioID string, currency string, rng string) (*PortfolioRisk, error) {
+	if rng == "" {
+		rng = "90d"
+	}
+	holdings, err := s.ListHoldings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	amounts := make(map[string]float64)
+	var coins []string
+	for _, h := range holdings {
+		if _, seen := amounts[h.CoinID]; !seen {
+			coins = append(coins, h.CoinID)
+		}
+		amounts[h.CoinID] += h.Amount
+	}
+
+	result := &PortfolioRisk{
+		Currency:     currency,
+		Range:        rng,
+		Benchmark:    market.RiskBenchmark,
+		RiskFreeRate: s.marketService.RiskFreeRate(),
+		Correlations: make(map[string]map[string]float64),
+		Unavailable:  make(map[string]string),
+	}
+
+	series := make(map[string][]risk.Point)
+	benchmark, err := s.marketService.DailySeries(market.RiskBenchmark, currency, rng)
+	if errors.Is(err, market.ErrInvalidRange) || errors.Is(err, market.ErrHistoryUnsupported) {
+		return nil, err
+	}
+	if err == nil {
+		series[market.RiskBenchmark] = benchmark
+	}
+	var priced []string
+	for _, coinID := range coins {
+		if coinID == market.RiskBenchmark && benchmark != nil {
+			priced = append(priced, coinID)
+			continue
+		}
+		points, err := s.marketService.DailySeries(coinID, currency, rng)
+		if err != nil {
+			result.Unavailable[coinID] = err.Error()
+			continue
+		}
+		series[coinID] = points
+		priced = append(priced, coinID)
+	}
+	coverage := risk.Coverage(series)
+	kept := priced[:0]
+	for _, coinID := range priced {
+		if coverage[coinID] < minRiskCoverage {
+			result.Unavailable[coinID] = fmt.Sprintf("price history covers only %.0f%% of %s", coverage[coinID]*100, rng)
+			delete(series, coinID)
+			continue
+		}
+		kept = append(kept, coinID)
+	}
+	priced = kept
+	if len(priced) == 0 {
+		return result, nil
+	}
+
+	times, prices := risk.Align(series, 24*time.Hour)
+	bench := prices[market.RiskBenchmark]
+	values := make([]float64, len(times))
+	for _, coinID := range priced {
+		for i, price := range prices[coinID] {
+			values[i] += amounts[coinID] * price
+		}
+	}
+	result.Portfolio = risk.Compute(times, values, bench, result.RiskFreeRate, market.RiskPeriodsPerYear)
+
+	dailyReturns := make(map[string][]float64, len(priced))
+	for _, coinID := range priced {
+		asset := AssetRisk{CoinID: coinID}
+		if n := len(times); n > 0 && values[n-1] > 0 {
+			asset.Weight = amounts[coinID] * prices[coinID][n-1] / values[n-1]
+		}
+		asset.Metrics = risk.Compute(times, prices[coinID], bench, result.RiskFreeRate, market.RiskPeriodsPerYear)
+		result.Assets = append(result.Assets, asset)
+		dailyReturns[coinID] = risk.Returns(prices[coinID])
+	}
+	for _, a := range priced {
+		result.Correlations[a] = make(map[string]float64, len(priced))
+		for _, b := range priced {
+			if corr, ok := risk.Correlation(dailyReturns[a], dailyReturns[b]); ok {
+				result.Correlations[a][b] = corr
+			}
+		}
+	}
+	return result, nil
+}
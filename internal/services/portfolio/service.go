@@ -3,6 +3,8 @@ package portfolio
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -10,99 +12,289 @@ import (
 	"github.com/faisal/crypto/backend/internal/config"
 	"github.com/faisal/crypto/backend/internal/models"
 	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/catalog"
 	"github.com/faisal/crypto/backend/internal/services/market"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+	"github.com/faisal/crypto/backend/internal/services/pricehistory"
+)
+
+var (
+	ErrNotFound            = repository.ErrNotFound
+	ErrVersionConflict     = repository.ErrVersionConflict
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrSnapshotTooSoon     = errors.New("a snapshot was taken too recently")
+	ErrUnknownCoin         = catalog.ErrUnknownCoin
+	ErrCatalogUnavailable  = catalog.ErrCatalogUnavailable
+	ErrOversold            = costbasis.ErrOversold
+	// ErrCoinInLedger refuses a manual holding of a coin the ledger tracks,
+	// which ListHoldings would hide behind the ledger position
+	ErrCoinInLedger = errors.New("coin has ledger entries, record changes to it as transactions")
 )
 
 type Service struct {
 	cfg           *config.Config
 	repo          repository.PortfolioRepository
 	marketService *market.Service
+	priceHistory  *pricehistory.Service
+	catalog       *catalog.Service
+	scheduler     snapshotScheduler
 }
 
-// NewService creates a portfolio service with in-memory storage (for development)
-func NewService(cfg *config.Config) *Service {
+// NewService creates a portfolio service with in-memory storage (for development).
+// marketService is shared with the market handler so both read the same cache.
+func NewService(cfg *config.Config, marketService *market.Service, priceHistory *pricehistory.Service, coins *catalog.Service) *Service {
 	repo := repository.NewMemoryPortfolioRepository()
+	priceHistory.TrackHeldCoins(repo.ListHeldCoins)
 	return &Service{
 		cfg:           cfg,
 		repo:          repo,
-		marketService: market.NewService(cfg),
+		marketService: marketService,
+		priceHistory:  priceHistory,
+		catalog:       coins,
 	}
 }
 
 // NewServiceWithMongo creates a portfolio service with MongoDB (for production)
 // Use this when MongoDB connection is ready
-func NewServiceWithMongo(cfg *config.Config, client *mongo.Client) *Service {
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client, marketService *market.Service, priceHistory *pricehistory.Service, coins *catalog.Service) *Service {
 	repo := repository.NewMongoPortfolioRepository(client.Database(cfg.MongoDBName))
+	priceHistory.TrackHeldCoins(repo.ListHeldCoins)
 	return &Service{
 		cfg:           cfg,
 		repo:          repo,
-		marketService: market.NewService(cfg),
+		marketService: marketService,
+		priceHistory:  priceHistory,
+		catalog:       coins,
 	}
 }
 
-func (s *Service) ListHoldings(ctx context.Context, userID string) ([]models.Holding, error) {
-	return s.repo.ListHoldings(ctx, userID)
+// ListHoldings returns a portfolio's manually entered holdings followed by
+// the positions derived from its transaction ledger. Ledger positions have a
+// zero ID and replace the manual holding of any coin the ledger covers.
+func (s *Service) ListHoldings(ctx context.Context, userID string, portfolioID string) ([]models.Holding, error) {
+	holdings, err := s.repo.ListHoldings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	holdings = withoutLedgerCoins(holdings, transactions)
+	return append(holdings, deriveHoldings(userID, portfolioID, transactions)...), nil
+}
+
+// withoutLedgerCoins drops manual holdings in coins that have ledger entries,
+// even ones netting to zero, so a coin is never counted from both sources.
+// Holding writes refuse such coins, so this only hides holdings that predate
+// the coin's first transaction.
+func withoutLedgerCoins(holdings []models.Holding, transactions []models.Transaction) []models.Holding {
+	inLedger := ledgerCoins(transactions)
+	var manual []models.Holding
+	for _, holding := range holdings {
+		if !inLedger[holding.CoinID] {
+			manual = append(manual, holding)
+		}
+	}
+	return manual
+}
+
+func ledgerCoins(transactions []models.Transaction) map[string]bool {
+	coins := make(map[string]bool)
+	for _, tx := range transactions {
+		coins[tx.CoinID] = true
+	}
+	return coins
+}
+
+// checkManualCoin refuses a manual holding of a coin with ledger entries.
+// The caller holds the portfolio's ledger lock.
+func (s *Service) checkManualCoin(ctx context.Context, userID string, portfolioID string, coinID string) error {
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if ledgerCoins(transactions)[coinID] {
+		return fmt.Errorf("%w: %s", ErrCoinInLedger, coinID)
+	}
+	return nil
 }
 
 type HoldingWithValue struct {
 	models.Holding
 	CurrentPrice float64 `json:"currentPrice"`
 	CurrentValue float64 `json:"currentValue"`
+	// PriceUnavailable is set when no price source knows the coin; the
+	// holding is then excluded from the total
+	PriceUnavailable bool `json:"priceUnavailable"`
+	// PriceSource names the provider that answered for CurrentPrice
+	PriceSource string `json:"priceSource,omitempty"`
+	// Cost basis fields are nil for manually entered holdings, which carry no
+	// acquisition price
+	CostBasis            *float64 `json:"costBasis"`
+	UnrealizedPnL        *float64 `json:"unrealizedPnl"`
+	UnrealizedPnLPercent *float64 `json:"unrealizedPnlPercent"`
+}
+
+// GetHoldingsWithValue values a portfolio's holdings in currency, which must
+// already be resolved with ResolveCurrency
+func (s *Service) GetHoldingsWithValue(ctx context.Context, userID string, portfolioID string, currency string) ([]HoldingWithValue, float64, error) {
+	holdings, err := s.ListHoldings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, 0, err
+	}
+	basis, err := s.CostBasis(ctx, userID, portfolioID, currency)
+	if err != nil {
+		return nil, 0, err
+	}
+	quotes, err := s.marketService.GetQuotes(coinIDs(holdings), currency)
+	if err != nil {
+		return nil, 0, err
+	}
+	prices := make(map[string]float64, len(quotes))
+	for id, quote := range quotes {
+		prices[id] = quote.Price
+	}
+	enriched, total := valueHoldings(holdings, basis, prices)
+	for i := range enriched {
+		enriched[i].PriceSource = quotes[enriched[i].CoinID].Source
+	}
+	return enriched, total, nil
 }
 
-func (s *Service) GetHoldingsWithValue(ctx context.Context, userID string) ([]HoldingWithValue, float64, error) {
-	holdings, err := s.ListHoldings(ctx, userID)
+// GetHoldingsWithValueAt values the portfolio as it stood at a past instant,
+// using only ledger entries up to that time and prices from the local price
+// history store. Manually entered holdings carry no date, so they are left
+// out. Stored prices are USD and are restated in currency at today's rate.
+func (s *Service) GetHoldingsWithValueAt(ctx context.Context, userID string, portfolioID string, at time.Time, currency string) ([]HoldingWithValue, float64, error) {
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
 	if err != nil {
 		return nil, 0, err
 	}
-	marketData, err := s.marketService.GetTopMarketData()
+	cutoff := models.ToPrimitiveDateTime(at)
+	var past []models.Transaction
+	for _, tx := range transactions {
+		if tx.Timestamp <= cutoff {
+			past = append(past, tx)
+		}
+	}
+	holdings := deriveHoldings(userID, portfolioID, past)
+
+	basis, err := s.costBasisOf(ctx, userID, portfolioID, past, currency)
+	if err != nil {
+		return nil, 0, err
+	}
+	prices, err := s.priceHistory.PricesAt(ctx, coinIDs(holdings), at)
+	if err != nil {
+		return nil, 0, err
+	}
+	rate, err := s.marketService.ConversionRate("usd", currency)
 	if err != nil {
 		return nil, 0, err
 	}
-	priceIndex := make(map[string]market.CoinMarket, len(marketData))
-	for _, coin := range marketData {
-		priceIndex[coin.ID] = coin
+	for id := range prices {
+		prices[id] *= rate
 	}
+	enriched, total := valueHoldings(holdings, basis, prices)
+	return enriched, total, nil
+}
+
+func coinIDs(holdings []models.Holding) []string {
+	ids := make([]string, 0, len(holdings))
+	for _, holding := range holdings {
+		ids = append(ids, holding.CoinID)
+	}
+	return ids
+}
 
+func valueHoldings(holdings []models.Holding, basis *costbasis.Result, prices map[string]float64) ([]HoldingWithValue, float64) {
 	var enriched []HoldingWithValue
 	var total float64
 	for _, holding := range holdings {
-		coin, ok := priceIndex[holding.CoinID]
-		if !ok {
-			continue
+		item := HoldingWithValue{Holding: holding}
+		price, priced := prices[holding.CoinID]
+		if priced {
+			item.CurrentPrice = price
+			item.CurrentValue = holding.Amount * price
+			total += item.CurrentValue
+		} else {
+			item.PriceUnavailable = true
 		}
-		value := holding.Amount * coin.CurrentPrice
-		enriched = append(enriched, HoldingWithValue{
-			Holding:      holding,
-			CurrentPrice: coin.CurrentPrice,
-			CurrentValue: value,
-		})
-		total += value
+		if pos, ok := basis.Positions[holding.CoinID]; ok && holding.ID.IsZero() {
+			cost := pos.CostBasis
+			item.CostBasis = &cost
+			if priced {
+				pnl := item.CurrentValue - cost
+				item.UnrealizedPnL = &pnl
+				if cost > 0 {
+					pct := pnl / cost * 100
+					item.UnrealizedPnLPercent = &pct
+				}
+			}
+		}
+		enriched = append(enriched, item)
 	}
-	return enriched, total, nil
+	return enriched, total
 }
 
+// CreateHolding rejects coin IDs missing from the coin catalog, since such a
+// holding could never be priced, and coins with ledger entries
 func (s *Service) CreateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
+	holding.CoinID = strings.ToLower(strings.TrimSpace(holding.CoinID))
 	if holding.UserID == "" || holding.CoinID == "" || holding.Amount <= 0 {
 		return nil, errors.New("invalid holding payload")
 	}
+	if err := s.catalog.Validate(holding.CoinID); err != nil {
+		return nil, err
+	}
+	holding.PortfolioID = models.PortfolioOf(holding.PortfolioID)
+	holding.Version = 1
+	unlock, err := s.repo.LockLedger(ctx, holding.UserID, holding.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := s.checkManualCoin(ctx, holding.UserID, holding.PortfolioID, holding.CoinID); err != nil {
+		return nil, err
+	}
 	return s.repo.CreateHolding(ctx, holding)
 }
 
-func (s *Service) DeleteHolding(ctx context.Context, id string, userID string) error {
-	return s.repo.DeleteHolding(ctx, id, userID)
+func (s *Service) GetHolding(ctx context.Context, id string, userID string, portfolioID string) (*models.Holding, error) {
+	return s.repo.GetHolding(ctx, id, userID, portfolioID)
 }
 
-func (s *Service) ListSnapshots(ctx context.Context, userID string) ([]models.Snapshot, error) {
-	return s.repo.ListSnapshots(ctx, userID)
-}
-
-func (s *Service) CreateSnapshot(ctx context.Context, snapshot models.Snapshot) (*models.Snapshot, error) {
-	if snapshot.UserID == "" || snapshot.TotalValue < 0 {
-		return nil, errors.New("invalid snapshot payload")
+// UpdateHolding saves holding if holding.Version is still current, returning
+// ErrVersionConflict when another write got there first and ErrCoinInLedger
+// when the ledger tracks the coin
+func (s *Service) UpdateHolding(ctx context.Context, holding models.Holding) (*models.Holding, error) {
+	holding.CoinID = strings.ToLower(strings.TrimSpace(holding.CoinID))
+	if holding.UserID == "" || holding.CoinID == "" || holding.Amount <= 0 {
+		return nil, errors.New("invalid holding payload")
 	}
-	if snapshot.Timestamp == 0 {
-		snapshot.Timestamp = models.ToPrimitiveDateTime(time.Now())
+	holding.PortfolioID = models.PortfolioOf(holding.PortfolioID)
+	// Only a changed coin is checked, so holdings predating the catalog can
+	// still have their amount edited
+	existing, err := s.repo.GetHolding(ctx, holding.ID.Hex(), holding.UserID, holding.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.CoinID != holding.CoinID {
+		if err := s.catalog.Validate(holding.CoinID); err != nil {
+			return nil, err
+		}
+	}
+	unlock, err := s.repo.LockLedger(ctx, holding.UserID, holding.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := s.checkManualCoin(ctx, holding.UserID, holding.PortfolioID, holding.CoinID); err != nil {
+		return nil, err
 	}
-	return s.repo.CreateSnapshot(ctx, snapshot)
+	return s.repo.UpdateHolding(ctx, holding)
+}
+
+func (s *Service) DeleteHolding(ctx context.Context, id string, userID string, portfolioID string) error {
+	return s.repo.DeleteHolding(ctx, id, userID, portfolioID)
 }
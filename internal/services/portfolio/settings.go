@@ -0,0 +1,128 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+)
+
+// GetSettings returns the user's stored settings, falling back to config defaults
+func (s *Service) GetSettings(ctx context.Context, userID string) (*models.PortfolioSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return &models.PortfolioSettings{
+			UserID:            userID,
+			CostBasisMethod:   s.cfg.DefaultCostBasisMethod,
+			Currency:          s.cfg.DefaultCurrency,
+			SnapshotFrequency: s.cfg.DefaultSnapshotFrequency,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Settings saved before currency support have no currency
+	if settings.Currency == "" {
+		settings.Currency = s.cfg.DefaultCurrency
+	}
+	if settings.SnapshotFrequency == "" {
+		settings.SnapshotFrequency = s.cfg.DefaultSnapshotFrequency
+	}
+	return settings, nil
+}
+
+func (s *Service) UpdateSettings(ctx context.Context, settings models.PortfolioSettings) (*models.PortfolioSettings, error) {
+	if settings.UserID == "" || !costbasis.Method(settings.CostBasisMethod).Valid() {
+		return nil, errors.New("invalid settings payload")
+	}
+	if _, ok := models.SnapshotInterval(settings.SnapshotFrequency); !ok && settings.SnapshotFrequency != models.SnapshotOff {
+		return nil, errors.New("snapshot frequency must be hourly, daily or off")
+	}
+	currency, err := s.marketService.NormalizeCurrency(settings.Currency)
+	if err != nil {
+		return nil, err
+	}
+	settings.Currency = currency
+	return s.repo.SaveSettings(ctx, settings)
+}
+
+// portfolioSettings overlays a portfolio's own base currency and lot-matching
+// method on the user's settings. An empty portfolioID yields the user's settings.
+func (s *Service) portfolioSettings(ctx context.Context, userID string, portfolioID string) (*models.PortfolioSettings, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil || portfolioID == "" {
+		return settings, err
+	}
+	portfolio, err := s.GetPortfolio(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio.BaseCurrency != "" {
+		settings.Currency = portfolio.BaseCurrency
+	}
+	if portfolio.CostBasisMethod != "" {
+		settings.CostBasisMethod = portfolio.CostBasisMethod
+	}
+	return settings, nil
+}
+
+// ResolveCurrency validates a requested valuation currency, falling back to
+// the portfolio's base currency and then the user's default when none is
+// given. An empty portfolioID skips the portfolio step.
+func (s *Service) ResolveCurrency(ctx context.Context, userID string, portfolioID string, requested string) (string, error) {
+	if requested == "" {
+		settings, err := s.portfolioSettings(ctx, userID, portfolioID)
+		if err != nil {
+			return "", err
+		}
+		requested = settings.Currency
+	}
+	return s.marketService.NormalizeCurrency(requested)
+}
+
+// CostBasis matches a portfolio's ledger using its lot-matching method, with
+// all prices restated in currency
+func (s *Service) CostBasis(ctx context.Context, userID string, portfolioID string, currency string) (*costbasis.Result, error) {
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	return s.costBasisOf(ctx, userID, portfolioID, transactions, currency)
+}
+
+func (s *Service) costBasisOf(ctx context.Context, userID string, portfolioID string, transactions []models.Transaction, currency string) (*costbasis.Result, error) {
+	settings, err := s.portfolioSettings(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := s.convertLedger(transactions, currency)
+	if err != nil {
+		return nil, err
+	}
+	return costbasis.Compute(converted, costbasis.Method(settings.CostBasisMethod))
+}
+
+// convertLedger restates unit prices quoted in other currencies into currency.
+// Current exchange rates are used, so cross-currency cost basis is an
+// approximation.
+func (s *Service) convertLedger(transactions []models.Transaction, currency string) ([]models.Transaction, error) {
+	rates := make(map[string]float64)
+	converted := make([]models.Transaction, len(transactions))
+	for i, tx := range transactions {
+		if tx.QuoteCurrency != currency {
+			rate, ok := rates[tx.QuoteCurrency]
+			if !ok {
+				var err error
+				if rate, err = s.marketService.ConversionRate(tx.QuoteCurrency, currency); err != nil {
+					return nil, err
+				}
+				rates[tx.QuoteCurrency] = rate
+			}
+			tx.UnitPrice *= rate
+			tx.QuoteCurrency = currency
+		}
+		converted[i] = tx
+	}
+	return converted, nil
+}
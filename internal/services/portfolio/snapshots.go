@@ -0,0 +1,259 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+// SnapshotMismatchError rejects a client-reported total that disagrees with
+// the server's own valuation
+type SnapshotMismatchError struct {
+	Reported float64
+	Computed float64
+	Currency string
+}
+
+func (e *SnapshotMismatchError) Error() string {
+	return fmt.Sprintf("reported total %.2f differs from computed total %.2f %s", e.Reported, e.Computed, e.Currency)
+}
+
+// TakeSnapshot values a portfolio now and stores the result. The total is
+// always the server's own; a client-reported total is only checked against it
+// and rejected with *SnapshotMismatchError when outside the configured tolerance.
+// Client requests within SnapshotMinIntervalSeconds of the latest snapshot
+// fail with ErrSnapshotTooSoon.
+func (s *Service) TakeSnapshot(ctx context.Context, userID string, portfolioID string, source string, reported *float64) (*models.Snapshot, error) {
+	if source == models.SnapshotSourceClient && s.cfg.SnapshotMinIntervalSeconds > 0 {
+		latest, err := s.repo.LatestSnapshot(ctx, userID, portfolioID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		minInterval := time.Duration(s.cfg.SnapshotMinIntervalSeconds) * time.Second
+		if latest != nil && time.Since(latest.Timestamp.Time()) < minInterval {
+			return nil, ErrSnapshotTooSoon
+		}
+	}
+	currency, err := s.ResolveCurrency(ctx, userID, portfolioID, "")
+	if err != nil {
+		return nil, err
+	}
+	holdings, total, err := s.GetHoldingsWithValue(ctx, userID, portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+	if reported != nil && !withinTolerance(*reported, total, float64(s.cfg.SnapshotTolerancePercent)) {
+		return nil, &SnapshotMismatchError{Reported: *reported, Computed: total, Currency: currency}
+	}
+	return s.repo.CreateSnapshot(ctx, models.Snapshot{
+		UserID:      userID,
+		PortfolioID: models.PortfolioOf(portfolioID),
+		TotalValue:  total,
+		Currency:    currency,
+		Source:      source,
+		PriceSource: priceSources(holdings),
+		Assets:      snapshotAssets(holdings),
+		Timestamp:   models.ToPrimitiveDateTime(time.Now()),
+	})
+}
+
+// priceSources lists the providers that priced holdings, comma-separated
+func priceSources(holdings []HoldingWithValue) string {
+	var sources []string
+	for _, h := range holdings {
+		if h.PriceSource != "" && !slices.Contains(sources, h.PriceSource) {
+			sources = append(sources, h.PriceSource)
+		}
+	}
+	sort.Strings(sources)
+	return strings.Join(sources, ",")
+}
+
+// snapshotAssets folds valued holdings into one entry per coin
+func snapshotAssets(holdings []HoldingWithValue) []models.SnapshotAsset {
+	index := make(map[string]int)
+	var assets []models.SnapshotAsset
+	for _, h := range holdings {
+		i, ok := index[h.CoinID]
+		if !ok {
+			i = len(assets)
+			index[h.CoinID] = i
+			assets = append(assets, models.SnapshotAsset{
+				CoinID:           h.CoinID,
+				Price:            h.CurrentPrice,
+				PriceUnavailable: h.PriceUnavailable,
+			})
+		}
+		assets[i].Amount += h.Amount
+		assets[i].Value += h.CurrentValue
+	}
+	return assets
+}
+
+// AssetPoint is one coin's position in a single snapshot
+type AssetPoint struct {
+	Timestamp        primitive.DateTime `json:"timestamp"`
+	Amount           float64            `json:"amount"`
+	Price            float64            `json:"price"`
+	Value            float64            `json:"value"`
+	PriceUnavailable bool               `json:"priceUnavailable,omitempty"`
+}
+
+// AssetSeries pivots snapshots into a time series per coin, keyed by the
+// currency the snapshots were valued in and then by coin ID, so values in
+// different currencies never share a series. A coin missing from a snapshot
+// was not held at that time and gets no point. Snapshots taken before
+// per-asset breakdowns contribute nothing.
+func AssetSeries(snapshots []models.Snapshot) map[string]map[string][]AssetPoint {
+	ordered := make([]models.Snapshot, len(snapshots))
+	copy(ordered, snapshots)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp < ordered[j].Timestamp
+	})
+
+	series := make(map[string]map[string][]AssetPoint)
+	for _, snapshot := range ordered {
+		if len(snapshot.Assets) == 0 {
+			continue
+		}
+		currency := snapshotCurrency(snapshot)
+		coins, ok := series[currency]
+		if !ok {
+			coins = make(map[string][]AssetPoint)
+			series[currency] = coins
+		}
+		for _, asset := range snapshot.Assets {
+			coins[asset.CoinID] = append(coins[asset.CoinID], AssetPoint{
+				Timestamp:        snapshot.Timestamp,
+				Amount:           asset.Amount,
+				Price:            asset.Price,
+				Value:            asset.Value,
+				PriceUnavailable: asset.PriceUnavailable,
+			})
+		}
+	}
+	return series
+}
+
+func withinTolerance(reported, computed, percent float64) bool {
+	if computed == 0 {
+		return reported == 0
+	}
+	return math.Abs(reported-computed)/math.Abs(computed)*100 <= percent
+}
+
+// StartSnapshots launches the snapshot scheduler. It is a no-op when
+// SNAPSHOT_CHECK_INTERVAL_SECONDS is 0 or it is already running.
+func (s *Service) StartSnapshots() {
+	s.scheduler.start(time.Duration(s.cfg.SnapshotCheckIntervalSeconds)*time.Second, s.runDueSnapshots)
+}
+
+// StopSnapshots halts the scheduler, waiting for an in-flight run
+func (s *Service) StopSnapshots(ctx context.Context) error {
+	return s.scheduler.stop(ctx)
+}
+
+// runDueSnapshots snapshots every portfolio whose owner's frequency period
+// has rolled over since its latest snapshot. Periods are aligned to UTC clock
+// hours and days, so restarts and check jitter do not cause drift.
+func (s *Service) runDueSnapshots(ctx context.Context) {
+	owners, err := s.repo.ListPortfolioOwners(ctx)
+	if err != nil {
+		log.Printf("portfolio: list snapshot owners: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	for _, userID := range owners {
+		if ctx.Err() != nil {
+			return
+		}
+		settings, err := s.GetSettings(ctx, userID)
+		if err != nil {
+			log.Printf("portfolio: snapshot settings for %s: %v", userID, err)
+			continue
+		}
+		interval, ok := models.SnapshotInterval(settings.SnapshotFrequency)
+		if !ok {
+			continue
+		}
+		periodStart := models.ToPrimitiveDateTime(now.Truncate(interval))
+
+		portfolios, err := s.ListPortfolios(ctx, userID)
+		if err != nil {
+			log.Printf("portfolio: list portfolios for %s: %v", userID, err)
+			continue
+		}
+		for _, p := range portfolios {
+			latest, err := s.repo.LatestSnapshot(ctx, userID, p.ID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				log.Printf("portfolio: latest snapshot for %s/%s: %v", userID, p.ID, err)
+				continue
+			}
+			if latest != nil && latest.Timestamp >= periodStart {
+				continue
+			}
+			if _, err := s.TakeSnapshot(ctx, userID, p.ID, models.SnapshotSourceScheduled, nil); err != nil {
+				log.Printf("portfolio: snapshot %s/%s: %v", userID, p.ID, err)
+			}
+		}
+	}
+}
+
+// snapshotScheduler runs a job on a fixed interval until stopped
+type snapshotScheduler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (sc *snapshotScheduler) start(interval time.Duration, run func(ctx context.Context)) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.cancel != nil || interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.cancel = cancel
+	sc.done = make(chan struct{})
+	go func() {
+		defer close(sc.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (sc *snapshotScheduler) stop(ctx context.Context) error {
+	sc.mu.Lock()
+	cancel, done := sc.cancel, sc.done
+	sc.cancel = nil
+	sc.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
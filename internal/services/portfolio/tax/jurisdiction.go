@@ -0,0 +1,84 @@
+// Package tax builds capital gains and income reports for a tax year from
+// matched disposals.
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+)
+
+var ErrUnknownJurisdiction = errors.New("unknown tax jurisdiction")
+
+// Jurisdiction is a tax jurisdiction with the configured long-term
+// holding period override applied
+type Jurisdiction struct {
+	models.TaxJurisdiction
+}
+
+// Codes lists the known jurisdiction codes
+func Codes() []string {
+	return models.TaxJurisdictionCodes()
+}
+
+// LookupJurisdiction returns the jurisdiction for code with any configured
+// long-term holding period override applied. An override of 0 drops the
+// short/long split.
+func LookupJurisdiction(code string, longTermDays map[string]int) (Jurisdiction, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	j, ok := models.LookupTaxJurisdiction(code)
+	if !ok {
+		return Jurisdiction{}, fmt.Errorf("%w %q, expected one of %s", ErrUnknownJurisdiction, code, strings.Join(Codes(), ", "))
+	}
+	if days, ok := longTermDays[code]; ok && days >= 0 {
+		j.LongTermYears, j.LongTermDays = 0, days
+	}
+	return Jurisdiction{j}, nil
+}
+
+// Term classifies a lot held from acquired to disposed. Holding periods are
+// counted in UTC calendar dates, so a lot sold on the anniversary of its
+// purchase, including a 29 February one, is still short-term. It returns ""
+// when the jurisdiction does not split gains by term.
+func (j Jurisdiction) Term(acquired, disposed time.Time) Term {
+	from, to := date(acquired), date(disposed)
+	var threshold time.Time
+	switch {
+	case j.LongTermDays > 0:
+		threshold = from.AddDate(0, 0, j.LongTermDays)
+	case j.LongTermYears > 0:
+		threshold = from.AddDate(j.LongTermYears, 0, 0)
+	default:
+		return ""
+	}
+	if to.After(threshold) {
+		return LongTerm
+	}
+	return ShortTerm
+}
+
+// date truncates t to midnight UTC
+func date(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Period returns the UTC bounds of the tax year starting in year; end is
+// exclusive
+func (j Jurisdiction) Period(year int) (time.Time, time.Time) {
+	start := time.Date(year, j.YearStartMonth, j.YearStartDay, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// YearOf returns the tax year, named by its starting calendar year, that t
+// falls in
+func (j Jurisdiction) YearOf(t time.Time) int {
+	year := t.UTC().Year()
+	if start, _ := j.Period(year); t.Before(start) {
+		return year - 1
+	}
+	return year
+}
@@ -0,0 +1,135 @@
+package tax
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func mustLookup(t *testing.T, code string, longTermDays map[string]int) Jurisdiction {
+	t.Helper()
+	j, err := LookupJurisdiction(code, longTermDays)
+	if err != nil {
+		t.Fatalf("LookupJurisdiction(%q) error = %v", code, err)
+	}
+	return j
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		code      string
+		year      int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{code: "us", year: 2024, wantStart: day(2024, 1, 1), wantEnd: day(2025, 1, 1)},
+		{code: "uk", year: 2024, wantStart: day(2024, 4, 6), wantEnd: day(2025, 4, 6)},
+		{code: "au", year: 2024, wantStart: day(2024, 7, 1), wantEnd: day(2025, 7, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			start, end := mustLookup(t, tt.code, nil).Period(tt.year)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("Period(%d) = %v, %v, want %v, %v", tt.year, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestYearOf(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		want int
+	}{
+		{name: "us new year", code: "us", at: day(2024, 1, 1), want: 2024},
+		{name: "us new year's eve", code: "us", at: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), want: 2024},
+		{name: "uk 5 April", code: "uk", at: time.Date(2024, 4, 5, 23, 59, 0, 0, time.UTC), want: 2023},
+		{name: "uk 6 April", code: "uk", at: day(2024, 4, 6), want: 2024},
+		{name: "au 30 June", code: "au", at: day(2024, 6, 30), want: 2023},
+		{name: "au 1 July", code: "au", at: day(2024, 7, 1), want: 2024},
+		{
+			// Still 30 June in UTC
+			name: "au offset timestamp",
+			code: "au",
+			at:   time.Date(2024, 7, 1, 8, 0, 0, 0, time.FixedZone("AEST", 10*60*60)),
+			want: 2023,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustLookup(t, tt.code, nil).YearOf(tt.at); got != tt.want {
+				t.Errorf("YearOf(%v) = %d, want %d", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerm(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		longTermDays map[string]int
+		acquired     time.Time
+		disposed     time.Time
+		want         Term
+	}{
+		{name: "same day", code: "us", acquired: day(2023, 3, 1), disposed: day(2023, 3, 1), want: ShortTerm},
+		{name: "on the anniversary", code: "us", acquired: day(2023, 3, 1), disposed: day(2024, 3, 1), want: ShortTerm},
+		{name: "day after the anniversary", code: "us", acquired: day(2023, 3, 1), disposed: day(2024, 3, 2), want: LongTerm},
+		{
+			// 366 days spanning 29 February 2024 is still the anniversary
+			name:     "anniversary across a leap day",
+			code:     "us",
+			acquired: day(2023, 6, 1),
+			disposed: day(2024, 6, 1),
+			want:     ShortTerm,
+		},
+		{name: "leap day purchase on 1 March", code: "us", acquired: day(2024, 2, 29), disposed: day(2025, 3, 1), want: ShortTerm},
+		{name: "leap day purchase on 2 March", code: "us", acquired: day(2024, 2, 29), disposed: day(2025, 3, 2), want: LongTerm},
+		{
+			name:     "times of day ignored",
+			code:     "de",
+			acquired: time.Date(2023, 3, 1, 23, 0, 0, 0, time.UTC),
+			disposed: time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC),
+			want:     LongTerm,
+		},
+		{name: "au anniversary", code: "au", acquired: day(2023, 7, 1), disposed: day(2024, 7, 1), want: ShortTerm},
+		{
+			name:         "days override",
+			code:         "us",
+			longTermDays: map[string]int{"us": 30},
+			acquired:     day(2024, 1, 1),
+			disposed:     day(2024, 2, 1),
+			want:         LongTerm,
+		},
+		{
+			name:         "days override threshold",
+			code:         "us",
+			longTermDays: map[string]int{"us": 30},
+			acquired:     day(2024, 1, 1),
+			disposed:     day(2024, 1, 31),
+			want:         ShortTerm,
+		},
+		{
+			name:         "override disables split",
+			code:         "us",
+			longTermDays: map[string]int{"us": 0},
+			acquired:     day(2020, 1, 1),
+			disposed:     day(2024, 1, 1),
+		},
+		{name: "no split in uk", code: "uk", acquired: day(2020, 1, 1), disposed: day(2024, 1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := mustLookup(t, tt.code, tt.longTermDays)
+			if got := j.Term(tt.acquired, tt.disposed); got != tt.want {
+				t.Errorf("Term(%v, %v) = %q, want %q", tt.acquired, tt.disposed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package tax
+
+import (
+	"sort"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/export"
+)
+
+type Term string
+
+const (
+	ShortTerm Term = "short"
+	LongTerm  Term = "long"
+)
+
+// DisposalLine is the part of a sale matched against one acquisition lot,
+// with proceeds split in proportion to quantity. Term is empty when the
+// jurisdiction does not distinguish holding periods. UnknownBasis marks a lot
+// transferred in from outside the ledger, whose cost basis and acquisition
+// date are only the transfer's.
+type DisposalLine struct {
+	TransactionID    string    `json:"transactionId"`
+	LotTransactionID string    `json:"lotTransactionId"`
+	CoinID           string    `json:"coinId"`
+	Acquired         time.Time `json:"acquired"`
+	Disposed         time.Time `json:"disposed"`
+	HoldingDays      int       `json:"holdingDays"`
+	Term             Term      `json:"term,omitempty"`
+	Quantity         float64   `json:"quantity"`
+	Proceeds         float64   `json:"proceeds"`
+	CostBasis        float64   `json:"costBasis"`
+	Gain             float64   `json:"gain"`
+	UnknownBasis     bool      `json:"unknownBasis,omitempty"`
+}
+
+// IncomeLine is coin received as income, valued when it was received
+type IncomeLine struct {
+	TransactionID string    `json:"transactionId"`
+	CoinID        string    `json:"coinId"`
+	IncomeType    string    `json:"incomeType"`
+	Received      time.Time `json:"received"`
+	Quantity      float64   `json:"quantity"`
+	UnitValue     float64   `json:"unitValue"`
+	Value         float64   `json:"value"`
+}
+
+type Summary struct {
+	Disposals     int                `json:"disposals"`
+	Proceeds      float64            `json:"proceeds"`
+	CostBasis     float64            `json:"costBasis"`
+	Gains         float64            `json:"gains"`
+	Losses        float64            `json:"losses"`
+	ShortTermGain float64            `json:"shortTermGain"`
+	LongTermGain  float64            `json:"longTermGain"`
+	NetGain       float64            `json:"netGain"`
+	Income        float64            `json:"income"`
+	IncomeByType  map[string]float64 `json:"incomeByType"`
+	// UnknownBasisDisposals counts lines whose cost basis must be checked
+	UnknownBasisDisposals int `json:"unknownBasisDisposals"`
+}
+
+type Report struct {
+	Year         int              `json:"year"`
+	Jurisdiction Jurisdiction     `json:"jurisdiction"`
+	Method       costbasis.Method `json:"method"`
+	Currency     string           `json:"currency"`
+	PeriodStart  time.Time        `json:"periodStart"`
+	PeriodEnd    time.Time        `json:"periodEnd"`
+	Summary      Summary          `json:"summary"`
+	Disposals    []DisposalLine   `json:"disposals"`
+	Income       []IncomeLine     `json:"income"`
+}
+
+// Build matches the whole ledger with method and reports the disposals and
+// income falling in the tax year. Unit prices must already be in currency.
+func Build(transactions []models.Transaction, method costbasis.Method, j Jurisdiction, year int, currency string) (*Report, error) {
+	matched, err := costbasis.Compute(transactions, method)
+	if err != nil {
+		return nil, err
+	}
+	start, end := j.Period(year)
+	report := &Report{
+		Year:         year,
+		Jurisdiction: j,
+		Method:       method,
+		Currency:     currency,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		Summary:      Summary{IncomeByType: map[string]float64{}},
+		Disposals:    []DisposalLine{},
+		Income:       []IncomeLine{},
+	}
+
+	for _, d := range matched.Disposals {
+		if d.Disposed.Before(start) || !d.Disposed.Before(end) {
+			continue
+		}
+		for _, m := range d.Matches {
+			line := DisposalLine{
+				TransactionID:    d.TransactionID,
+				LotTransactionID: m.LotTransactionID,
+				CoinID:           d.CoinID,
+				Acquired:         m.Acquired,
+				Disposed:         d.Disposed,
+				HoldingDays:      int(date(d.Disposed).Sub(date(m.Acquired)).Hours() / 24),
+				Term:             j.Term(m.Acquired, d.Disposed),
+				Quantity:         m.Quantity,
+				CostBasis:        m.Quantity * m.UnitCost,
+				UnknownBasis:     m.UnknownBasis,
+			}
+			if d.Quantity > 0 {
+				line.Proceeds = d.Proceeds * m.Quantity / d.Quantity
+			}
+			line.Gain = line.Proceeds - line.CostBasis
+			report.add(line)
+		}
+	}
+
+	for _, tx := range transactions {
+		at := tx.Timestamp.Time().UTC()
+		if tx.Type != models.TransactionIncome || at.Before(start) || !at.Before(end) {
+			continue
+		}
+		line := IncomeLine{
+			TransactionID: tx.ID.Hex(),
+			CoinID:        tx.CoinID,
+			IncomeType:    tx.IncomeType,
+			Received:      at,
+			Quantity:      tx.Quantity,
+			UnitValue:     tx.UnitPrice,
+			Value:         tx.Quantity * tx.UnitPrice,
+		}
+		report.Income = append(report.Income, line)
+		report.Summary.Income += line.Value
+		report.Summary.IncomeByType[line.IncomeType] += line.Value
+	}
+	sort.SliceStable(report.Income, func(a, b int) bool {
+		return report.Income[a].Received.Before(report.Income[b].Received)
+	})
+	return report, nil
+}
+
+func (r *Report) add(line DisposalLine) {
+	r.Disposals = append(r.Disposals, line)
+
+	s := &r.Summary
+	s.Disposals++
+	s.Proceeds += line.Proceeds
+	s.CostBasis += line.CostBasis
+	s.NetGain += line.Gain
+	if line.UnknownBasis {
+		s.UnknownBasisDisposals++
+	}
+	if line.Gain >= 0 {
+		s.Gains += line.Gain
+	} else {
+		s.Losses -= line.Gain
+	}
+	switch line.Term {
+	case ShortTerm:
+		s.ShortTermGain += line.Gain
+	case LongTerm:
+		s.LongTermGain += line.Gain
+	}
+}
+
+// WriteDisposals writes one row per disposal line
+func (r *Report) WriteDisposals(w export.Writer) error {
+	if err := w.Header("disposed", "acquired", "coinId", "quantity", "proceeds", "costBasis", "gain",
+		"holdingDays", "term", "unknownBasis", "currency", "transactionId", "lotTransactionId"); err != nil {
+		return err
+	}
+	for _, line := range r.Disposals {
+		if err := w.Row(line.Disposed, line.Acquired, line.CoinID, line.Quantity, line.Proceeds, line.CostBasis,
+			line.Gain, line.HoldingDays, string(line.Term), line.UnknownBasis, r.Currency, line.TransactionID,
+			line.LotTransactionID); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// WriteIncome writes one row per income event
+func (r *Report) WriteIncome(w export.Writer) error {
+	if err := w.Header("received", "coinId", "incomeType", "quantity", "unitValue", "value", "currency", "transactionId"); err != nil {
+		return err
+	}
+	for _, line := range r.Income {
+		if err := w.Row(line.Received, line.CoinID, line.IncomeType, line.Quantity, line.UnitValue,
+			line.Value, r.Currency, line.TransactionID); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
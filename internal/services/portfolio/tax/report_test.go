@@ -0,0 +1,206 @@
+package tax
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+)
+
+func tx(kind models.TransactionType, quantity, unitPrice float64, at time.Time) models.Transaction {
+	return models.Transaction{
+		ID:            primitive.NewObjectID(),
+		CoinID:        "bitcoin",
+		Type:          kind,
+		Quantity:      quantity,
+		UnitPrice:     unitPrice,
+		QuoteCurrency: "usd",
+		Timestamp:     models.ToPrimitiveDateTime(at),
+	}
+}
+
+func ledger() []models.Transaction {
+	staking := tx(models.TransactionIncome, 0.5, 200, day(2024, 5, 1))
+	staking.IncomeType = models.IncomeStaking
+	return []models.Transaction{
+		tx(models.TransactionBuy, 2, 100, day(2023, 1, 10)),
+		tx(models.TransactionBuy, 1, 300, day(2024, 3, 1)),
+		staking,
+		// 1.5 long-term from the first lot
+		tx(models.TransactionSell, 1.5, 400, day(2024, 6, 1)),
+		// 0.5 long-term from the first lot, 0.5 short-term from the second
+		tx(models.TransactionSell, 1, 250, day(2024, 9, 1)),
+		tx(models.TransactionSell, 0.5, 500, day(2025, 2, 1)),
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name          string
+		code          string
+		year          int
+		wantDisposals int
+		want          Summary
+	}{
+		{
+			name:          "us splits by term",
+			code:          "us",
+			year:          2024,
+			wantDisposals: 3,
+			want: Summary{
+				Disposals:     3,
+				Proceeds:      850,
+				CostBasis:     350,
+				Gains:         525,
+				Losses:        25,
+				ShortTermGain: -25,
+				LongTermGain:  525,
+				NetGain:       500,
+				Income:        100,
+			},
+		},
+		{
+			// Runs from 6 April 2024, so the February 2025 sale of the rest
+			// of the second lot is included, and no gain is split by term
+			name:          "uk year spanning two calendar years",
+			code:          "uk",
+			year:          2024,
+			wantDisposals: 4,
+			want: Summary{
+				Disposals: 4,
+				Proceeds:  1100,
+				CostBasis: 500,
+				Gains:     625,
+				Losses:    25,
+				NetGain:   600,
+				Income:    100,
+			},
+		},
+		{name: "year without activity", code: "us", year: 2023},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Build(ledger(), costbasis.FIFO, mustLookup(t, tt.code, nil), tt.year, "usd")
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if len(report.Disposals) != tt.wantDisposals {
+				t.Fatalf("Build() disposals = %d, want %d", len(report.Disposals), tt.wantDisposals)
+			}
+			if tt.want.Disposals == 0 {
+				return
+			}
+			got := report.Summary
+			for _, c := range []struct {
+				field     string
+				got, want float64
+			}{
+				{"proceeds", got.Proceeds, tt.want.Proceeds},
+				{"costBasis", got.CostBasis, tt.want.CostBasis},
+				{"gains", got.Gains, tt.want.Gains},
+				{"losses", got.Losses, tt.want.Losses},
+				{"shortTermGain", got.ShortTermGain, tt.want.ShortTermGain},
+				{"longTermGain", got.LongTermGain, tt.want.LongTermGain},
+				{"netGain", got.NetGain, tt.want.NetGain},
+				{"income", got.Income, tt.want.Income},
+			} {
+				if math.Abs(c.got-c.want) > 1e-9 {
+					t.Errorf("Summary.%s = %v, want %v", c.field, c.got, c.want)
+				}
+			}
+			if got.IncomeByType[models.IncomeStaking] != tt.want.Income {
+				t.Errorf("Summary.IncomeByType = %v, want staking %v", got.IncomeByType, tt.want.Income)
+			}
+		})
+	}
+}
+
+func TestBuildOversold(t *testing.T) {
+	transactions := []models.Transaction{
+		tx(models.TransactionBuy, 1, 100, day(2024, 1, 10)),
+		tx(models.TransactionSell, 2, 200, day(2024, 6, 1)),
+	}
+	_, err := Build(transactions, costbasis.FIFO, mustLookup(t, "us", nil), 2024, "usd")
+	if !errors.Is(err, costbasis.ErrOversold) {
+		t.Fatalf("Build() error = %v, want %v", err, costbasis.ErrOversold)
+	}
+}
+
+func TestBuildTransfers(t *testing.T) {
+	buy := tx(models.TransactionBuy, 1, 100, day(2023, 1, 10))
+	tests := []struct {
+		name        string
+		ledger      []models.Transaction
+		wantLot     string
+		wantBasis   float64
+		wantTerm    Term
+		wantUnknown bool
+	}{
+		{
+			// Received back at market price, but the lot keeps its date and cost
+			name: "out to a wallet and back",
+			ledger: []models.Transaction{
+				buy,
+				tx(models.TransactionTransferOut, 1, 0, day(2024, 2, 1)),
+				tx(models.TransactionTransferIn, 1, 5000, day(2024, 2, 2)),
+				tx(models.TransactionSell, 1, 400, day(2024, 6, 1)),
+			},
+			wantLot:   buy.ID.Hex(),
+			wantBasis: 100,
+			wantTerm:  LongTerm,
+		},
+		{
+			// The network fee leaves 0.01 in transit
+			name: "received less than sent",
+			ledger: []models.Transaction{
+				buy,
+				tx(models.TransactionTransferOut, 1, 0, day(2024, 2, 1)),
+				tx(models.TransactionTransferIn, 0.99, 0, day(2024, 2, 2)),
+				tx(models.TransactionSell, 0.99, 400, day(2024, 6, 1)),
+			},
+			wantLot:   buy.ID.Hex(),
+			wantBasis: 99,
+			wantTerm:  LongTerm,
+		},
+		{
+			name: "received from outside the ledger",
+			ledger: []models.Transaction{
+				tx(models.TransactionTransferIn, 1, 0, day(2024, 2, 1)),
+				tx(models.TransactionSell, 1, 400, day(2024, 6, 1)),
+			},
+			wantTerm:    ShortTerm,
+			wantUnknown: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Build(tt.ledger, costbasis.FIFO, mustLookup(t, "us", nil), 2024, "usd")
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if len(report.Disposals) != 1 {
+				t.Fatalf("Build() disposals = %+v, want 1", report.Disposals)
+			}
+			line := report.Disposals[0]
+			if tt.wantLot != "" && line.LotTransactionID != tt.wantLot {
+				t.Errorf("lot = %s, want %s", line.LotTransactionID, tt.wantLot)
+			}
+			if math.Abs(line.CostBasis-tt.wantBasis) > 1e-9 || line.Term != tt.wantTerm || line.UnknownBasis != tt.wantUnknown {
+				t.Errorf("line = basis %v, %q, unknown %v, want %v, %q, %v",
+					line.CostBasis, line.Term, line.UnknownBasis, tt.wantBasis, tt.wantTerm, tt.wantUnknown)
+			}
+			wantCount := 0
+			if tt.wantUnknown {
+				wantCount = 1
+			}
+			if report.Summary.UnknownBasisDisposals != wantCount {
+				t.Errorf("Summary.UnknownBasisDisposals = %d, want %d", report.Summary.UnknownBasisDisposals, wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/tax"
+)
+
+var (
+	ErrInvalidCostBasisMethod = errors.New("method must be fifo, lifo, hifo or average")
+	// ErrTaxCurrencyMismatch refuses a tax report in a currency other than
+	// the one the ledger is priced in. Only current exchange rates are
+	// available, and gains must be stated at the rate of each trade date.
+	ErrTaxCurrencyMismatch = errors.New("tax report currency differs from the ledger's quote currency")
+)
+
+// TaxQuery selects a tax report. Zero values fall back to the current tax
+// year, the portfolio's lot-matching method and the configured jurisdiction.
+// Currency must already be resolved with ResolveCurrency.
+type TaxQuery struct {
+	Year         int
+	Method       string
+	Jurisdiction string
+	Currency     string
+}
+
+// TaxReport reports a portfolio's realized gains and income for one tax
+// year. Every priced entry must be quoted in the report currency, otherwise
+// ErrTaxCurrencyMismatch names the currencies found.
+func (s *Service) TaxReport(ctx context.Context, userID string, portfolioID string, query TaxQuery) (*tax.Report, error) {
+	code := query.Jurisdiction
+	if code == "" {
+		code = s.cfg.TaxJurisdiction
+	}
+	jurisdiction, err := tax.LookupJurisdiction(code, s.cfg.TaxLongTermDays)
+	if err != nil {
+		return nil, err
+	}
+	year := query.Year
+	if year == 0 {
+		year = jurisdiction.YearOf(time.Now())
+	}
+
+	method := costbasis.Method(query.Method)
+	if method == "" {
+		settings, err := s.portfolioSettings(ctx, userID, portfolioID)
+		if err != nil {
+			return nil, err
+		}
+		method = costbasis.Method(settings.CostBasisMethod)
+	}
+	if !method.Valid() {
+		return nil, ErrInvalidCostBasisMethod
+	}
+
+	transactions, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	var quoted []string
+	for _, tx := range transactions {
+		// Zero-priced entries such as fees read the same in any currency
+		if tx.UnitPrice != 0 && tx.QuoteCurrency != query.Currency && !slices.Contains(quoted, tx.QuoteCurrency) {
+			quoted = append(quoted, tx.QuoteCurrency)
+		}
+	}
+	if len(quoted) > 0 {
+		slices.Sort(quoted)
+		return nil, fmt.Errorf("%w: ledger has prices in %s, report requested in %s",
+			ErrTaxCurrencyMismatch, strings.Join(quoted, ", "), query.Currency)
+	}
+	return tax.Build(transactions, method, jurisdiction, year, query.Currency)
+}
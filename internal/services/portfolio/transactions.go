@@ -0,0 +1,234 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/services/portfolio/costbasis"
+)
+
+// balance is a running coin balance. Inflow, everything ever added, scales
+// the float noise tolerated when a position is fully disposed.
+type balance struct {
+	amount float64
+	inflow float64
+}
+
+func (b *balance) add(delta float64) {
+	b.amount += delta
+	if delta > 0 {
+		b.inflow += delta
+	}
+}
+
+// held reports whether a positive amount remains beyond float noise
+func (b balance) held() bool {
+	return b.amount > 0 && !costbasis.Negligible(b.amount, b.inflow)
+}
+
+// overdrawn reports whether the amount is negative beyond float noise
+func (b balance) overdrawn() bool {
+	return b.amount < 0 && !costbasis.Negligible(b.amount, b.inflow)
+}
+
+// coinBalances tracks a balance per coin ID
+type coinBalances map[string]*balance
+
+func (m coinBalances) add(coinID string, delta float64) *balance {
+	b, ok := m[coinID]
+	if !ok {
+		b = &balance{}
+		m[coinID] = b
+	}
+	b.add(delta)
+	return b
+}
+
+func (s *Service) ListTransactions(ctx context.Context, userID string, portfolioID string) ([]models.Transaction, error) {
+	return s.repo.ListTransactions(ctx, userID, portfolioID)
+}
+
+// GetTransaction returns a ledger entry, reporting ErrNotFound when it
+// belongs to a different portfolio
+func (s *Service) GetTransaction(ctx context.Context, id string, userID string, portfolioID string) (*models.Transaction, error) {
+	tx, err := s.repo.GetTransaction(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if models.PortfolioOf(tx.PortfolioID) != portfolioID {
+		return nil, ErrNotFound
+	}
+	return tx, nil
+}
+
+// CreateTransaction rejects coin IDs missing from the coin catalog, since
+// such an entry could never be priced. Ledger writes to a portfolio hold its
+// ledger lock from the balance check through the write.
+func (s *Service) CreateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	tx, err := s.normalizeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.catalog.Validate(tx.CoinID); err != nil {
+		return nil, err
+	}
+	unlock, err := s.repo.LockLedger(ctx, tx.UserID, tx.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	existing, err := s.repo.ListTransactions(ctx, tx.UserID, tx.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLedger(append(existing, tx)); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateTransaction(ctx, tx)
+}
+
+func (s *Service) UpdateTransaction(ctx context.Context, tx models.Transaction) (*models.Transaction, error) {
+	tx, err := s.normalizeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := s.repo.LockLedger(ctx, tx.UserID, tx.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	existing, err := s.repo.ListTransactions(ctx, tx.UserID, tx.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	var previous *models.Transaction
+	for i := range existing {
+		if existing[i].ID == tx.ID {
+			prev := existing[i]
+			previous = &prev
+			existing[i] = tx
+		}
+	}
+	if previous == nil {
+		return nil, ErrNotFound
+	}
+	// As with holdings, only a changed coin is checked against the catalog
+	if previous.CoinID != tx.CoinID {
+		if err := s.catalog.Validate(tx.CoinID); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkLedger(existing); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateTransaction(ctx, tx)
+}
+
+func (s *Service) DeleteTransaction(ctx context.Context, id string, userID string, portfolioID string) error {
+	unlock, err := s.repo.LockLedger(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	existing, err := s.repo.ListTransactions(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	found := false
+	remaining := existing[:0:0]
+	for _, tx := range existing {
+		if tx.ID.Hex() != id {
+			remaining = append(remaining, tx)
+		} else {
+			found = true
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	if err := checkLedger(remaining); err != nil {
+		return err
+	}
+	return s.repo.DeleteTransaction(ctx, id, userID)
+}
+
+// normalizeTransaction validates a ledger entry and fills in defaults. The
+// quote currency must be a supported one, since every valuation of the
+// ledger has to convert it.
+func (s *Service) normalizeTransaction(tx models.Transaction) (models.Transaction, error) {
+	tx.CoinID = strings.ToLower(strings.TrimSpace(tx.CoinID))
+	if tx.UserID == "" || tx.CoinID == "" || !tx.Type.Valid() || tx.Quantity <= 0 || tx.UnitPrice < 0 {
+		return tx, errors.New("invalid transaction payload")
+	}
+	tx.IncomeType = strings.ToLower(strings.TrimSpace(tx.IncomeType))
+	if tx.Type != models.TransactionIncome {
+		tx.IncomeType = ""
+	} else if tx.IncomeType == "" {
+		tx.IncomeType = models.IncomeReward
+	} else if !models.ValidIncomeType(tx.IncomeType) {
+		return tx, errors.New("income type must be staking, airdrop, mining, interest or reward")
+	}
+	tx.PortfolioID = models.PortfolioOf(tx.PortfolioID)
+	tx.QuoteCurrency = strings.ToLower(strings.TrimSpace(tx.QuoteCurrency))
+	if tx.QuoteCurrency == "" {
+		tx.QuoteCurrency = "usd"
+	}
+	if _, err := s.marketService.NormalizeCurrency(tx.QuoteCurrency); err != nil {
+		return tx, fmt.Errorf("quote currency: %w", err)
+	}
+	if tx.Timestamp == 0 {
+		tx.Timestamp = models.ToPrimitiveDateTime(time.Now())
+	}
+	return tx, nil
+}
+
+// checkLedger replays transactions in time order and rejects any sequence
+// that would take a coin balance below zero
+func checkLedger(transactions []models.Transaction) error {
+	ordered := make([]models.Transaction, len(transactions))
+	copy(ordered, transactions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp < ordered[j].Timestamp
+	})
+
+	balances := make(coinBalances)
+	for _, tx := range ordered {
+		if balances.add(tx.CoinID, tx.QuantityDelta()).overdrawn() {
+			return fmt.Errorf("%w: %s balance would go negative at %s",
+				ErrInsufficientBalance, tx.CoinID, tx.Timestamp.Time().UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// deriveHoldings folds a portfolio's ledger into one holding per coin with a
+// positive balance
+func deriveHoldings(userID string, portfolioID string, transactions []models.Transaction) []models.Holding {
+	balances := make(coinBalances)
+	var order []string
+	for _, tx := range transactions {
+		if _, seen := balances[tx.CoinID]; !seen {
+			order = append(order, tx.CoinID)
+		}
+		balances.add(tx.CoinID, tx.QuantityDelta())
+	}
+
+	var holdings []models.Holding
+	for _, coinID := range order {
+		if !balances[coinID].held() {
+			continue
+		}
+		holdings = append(holdings, models.Holding{
+			UserID:      userID,
+			PortfolioID: portfolioID,
+			CoinID:      coinID,
+			Amount:      balances[coinID].amount,
+		})
+	}
+	return holdings
+}
@@ -0,0 +1,331 @@
+package pricehistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/market"
+)
+
+var (
+	// ErrNoPrice is returned when no stored price is close enough to the requested time
+	ErrNoPrice      = errors.New("no stored price")
+	ErrInvalidRange = errors.New("invalid backfill range")
+	ErrJobNotFound  = errors.New("backfill job not found")
+	ErrBackfillBusy = errors.New("too many backfills pending, try again later")
+)
+
+const (
+	// maxRunningBackfills caps how many backfills fetch upstream at once
+	maxRunningBackfills = 2
+	// maxPendingBackfills caps running and queued backfills together
+	maxPendingBackfills = 20
+	// backfillRetention is how long a finished job can still be looked up
+	backfillRetention = time.Hour
+)
+
+type Service struct {
+	cfg           *config.Config
+	repo          repository.PriceRepository
+	marketService *market.Service
+
+	mu    sync.Mutex
+	jobs  map[string]*BackfillJob
+	slots chan struct{}
+
+	heldMu sync.RWMutex
+	held   func(ctx context.Context) ([]string, error)
+}
+
+// NewService creates a price history service with in-memory storage (for development)
+func NewService(cfg *config.Config, marketService *market.Service) *Service {
+	return newService(cfg, repository.NewMemoryPriceRepository(), marketService)
+}
+
+// NewServiceWithMongo creates a price history service backed by MongoDB
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client, marketService *market.Service) *Service {
+	return newService(cfg, repository.NewMongoPriceRepository(client.Database(cfg.MongoDBName)), marketService)
+}
+
+// newService subscribes the store to market refreshes so every polled price is recorded
+func newService(cfg *config.Config, repo repository.PriceRepository, marketService *market.Service) *Service {
+	s := &Service{
+		cfg:           cfg,
+		repo:          repo,
+		marketService: marketService,
+		jobs:          make(map[string]*BackfillJob),
+		slots:         make(chan struct{}, maxRunningBackfills),
+	}
+	marketService.OnUpdate(s.record)
+	return s
+}
+
+// TrackHeldCoins registers fn as the source of coins held in portfolios.
+// Their prices are recorded on every refresh along with the polled top coins.
+func (s *Service) TrackHeldCoins(fn func(ctx context.Context) ([]string, error)) {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+	s.held = fn
+}
+
+// record stores polled prices, plus current prices for held coins outside
+// the polled payload. The store is USD-only; other currencies are restated
+// on read.
+func (s *Service) record(data *market.MarketData) {
+	if data.Currency != "usd" {
+		return
+	}
+	records := make([]models.PriceRecord, 0, len(data.Coins))
+	polled := make(map[string]bool, len(data.Coins))
+	for _, coin := range data.Coins {
+		polled[coin.ID] = true
+		records = append(records, models.PriceRecord{
+			CoinID:    coin.ID,
+			Timestamp: models.ToPrimitiveDateTime(data.FetchedAt),
+			Price:     coin.CurrentPrice,
+			Source:    s.source(coin.Source),
+		})
+	}
+	// Market listeners must not block the refresh
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		held, err := s.heldRecords(ctx, polled)
+		if err != nil {
+			log.Printf("pricehistory: record held coin prices: %v", err)
+		}
+		if err := s.repo.SavePrices(ctx, append(records, held...)); err != nil {
+			log.Printf("pricehistory: record polled prices: %v", err)
+		}
+	}()
+}
+
+// heldRecords prices the held coins missing from polled
+func (s *Service) heldRecords(ctx context.Context, polled map[string]bool) ([]models.PriceRecord, error) {
+	s.heldMu.RLock()
+	held := s.held
+	s.heldMu.RUnlock()
+	if held == nil {
+		return nil, nil
+	}
+	coins, err := held(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, coinID := range coins {
+		if !polled[coinID] {
+			missing = append(missing, coinID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	quotes, err := s.marketService.GetQuotes(missing, "usd")
+	if err != nil {
+		return nil, err
+	}
+	now := models.ToPrimitiveDateTime(time.Now())
+	records := make([]models.PriceRecord, 0, len(quotes))
+	for coinID, quote := range quotes {
+		records = append(records, models.PriceRecord{
+			CoinID:    coinID,
+			Timestamp: now,
+			Price:     quote.Price,
+			Source:    s.source(quote.Source),
+		})
+	}
+	return records, nil
+}
+
+// source names the provider a price came from, falling back to the
+// configured chain when the provider did not say
+func (s *Service) source(provider string) string {
+	if provider == "" {
+		return s.marketService.ProviderName()
+	}
+	return provider
+}
+
+// PriceAt returns the latest stored price for coinID at or before t. Records
+// older than the configured tolerance are treated as missing.
+func (s *Service) PriceAt(ctx context.Context, coinID string, t time.Time) (*models.PriceRecord, error) {
+	record, err := s.repo.PriceAt(ctx, coinID, t)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w for %s at %s", ErrNoPrice, coinID, t.UTC().Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, err
+	}
+	maxAge := time.Duration(s.cfg.PriceLookupToleranceHours) * time.Hour
+	if t.Sub(record.Timestamp.Time()) > maxAge {
+		return nil, fmt.Errorf("%w for %s within %s of %s", ErrNoPrice, coinID, maxAge, t.UTC().Format(time.RFC3339))
+	}
+	return record, nil
+}
+
+// PricesAt resolves PriceAt for several coins; coins without a usable
+// record are absent from the result
+func (s *Service) PricesAt(ctx context.Context, coinIDs []string, t time.Time) (map[string]float64, error) {
+	prices := make(map[string]float64, len(coinIDs))
+	for _, coinID := range coinIDs {
+		if _, done := prices[coinID]; done {
+			continue
+		}
+		record, err := s.PriceAt(ctx, coinID, t)
+		if errors.Is(err, ErrNoPrice) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		prices[coinID] = record.Price
+	}
+	return prices, nil
+}
+
+func (s *Service) ListPrices(ctx context.Context, coinID string, from, to time.Time) ([]models.PriceRecord, error) {
+	return s.repo.ListPrices(ctx, coinID, from, to)
+}
+
+type BackfillStatus string
+
+const (
+	BackfillQueued  BackfillStatus = "queued"
+	BackfillRunning BackfillStatus = "running"
+	BackfillDone    BackfillStatus = "done"
+	BackfillFailed  BackfillStatus = "failed"
+)
+
+type BackfillJob struct {
+	ID         string         `json:"id"`
+	CoinID     string         `json:"coinId"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Status     BackfillStatus `json:"status"`
+	Stored     int            `json:"stored"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt *time.Time     `json:"finishedAt"`
+}
+
+// StartBackfill fetches coinID's upstream history between from and to in the
+// background and stores it. Poll GetBackfill with the returned job ID. A
+// request matching a job that is still pending or recently succeeded
+// returns that job; at most maxRunningBackfills run at once and further
+// ones queue, up to maxPendingBackfills.
+func (s *Service) StartBackfill(coinID string, from, to time.Time) (*BackfillJob, error) {
+	if coinID == "" || !from.Before(to) || to.After(time.Now().Add(time.Minute)) {
+		return nil, ErrInvalidRange
+	}
+	from, to = from.UTC().Truncate(time.Minute), to.UTC().Truncate(time.Minute)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneJobs()
+	pending := 0
+	for _, job := range s.jobs {
+		if job.CoinID == coinID && job.From.Equal(from) && job.To.Equal(to) && job.Status != BackfillFailed {
+			copied := *job
+			return &copied, nil
+		}
+		if job.FinishedAt == nil {
+			pending++
+		}
+	}
+	if pending >= maxPendingBackfills {
+		return nil, ErrBackfillBusy
+	}
+
+	job := &BackfillJob{
+		ID:        primitive.NewObjectID().Hex(),
+		CoinID:    coinID,
+		From:      from,
+		To:        to,
+		Status:    BackfillQueued,
+		StartedAt: time.Now().UTC(),
+	}
+	s.jobs[job.ID] = job
+	go s.runBackfill(job)
+
+	copied := *job
+	return &copied, nil
+}
+
+func (s *Service) GetBackfill(id string) (*BackfillJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneJobs()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// pruneJobs forgets jobs finished more than backfillRetention ago. s.mu must be held.
+func (s *Service) pruneJobs() {
+	cutoff := time.Now().Add(-backfillRetention)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func (s *Service) runBackfill(job *BackfillJob) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.mu.Lock()
+	job.Status = BackfillRunning
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	stored, err := s.backfill(ctx, job.CoinID, job.From, job.To)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.Stored = stored
+	if err != nil {
+		job.Status = BackfillFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = BackfillDone
+}
+
+func (s *Service) backfill(ctx context.Context, coinID string, from, to time.Time) (int, error) {
+	points, err := s.marketService.GetHistoryRange(ctx, coinID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	records := make([]models.PriceRecord, 0, len(points))
+	for _, point := range points {
+		records = append(records, models.PriceRecord{
+			CoinID:    coinID,
+			Timestamp: models.ToPrimitiveDateTime(point.Timestamp),
+			Price:     point.Price,
+			Source:    "backfill:" + s.source(point.Source),
+		})
+	}
+	if err := s.repo.SavePrices(ctx, records); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
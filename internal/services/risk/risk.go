@@ -0,0 +1,246 @@
+// Package risk computes volatility, drawdown, risk-adjusted return and
+// co-movement statistics over aligned price or value series.
+package risk
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Point is one observation of a price or portfolio value
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Drawdown is the largest peak-to-trough fall in a series, as a fraction of
+// the peak
+type Drawdown struct {
+	MaxDrawdown float64   `json:"maxDrawdown"`
+	Peak        time.Time `json:"peak"`
+	PeakValue   float64   `json:"peakValue"`
+	Trough      time.Time `json:"trough"`
+	TroughValue float64   `json:"troughValue"`
+}
+
+// Metrics summarises a series. Ratio fields are nil when the series is too
+// short or flat for them to be defined. Volatility is annualized; Sharpe and
+// Sortino are annualized excess returns over the risk-free rate. Start and
+// End are the first and last observations actually used, which may be later
+// than the requested range when a series has a shorter history.
+type Metrics struct {
+	Observations int       `json:"observations"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	TotalReturn  float64   `json:"totalReturn"`
+	Volatility   *float64  `json:"volatility"`
+	Drawdown     Drawdown  `json:"drawdown"`
+	Sharpe       *float64  `json:"sharpe"`
+	Sortino      *float64  `json:"sortino"`
+	Beta         *float64  `json:"beta"` // against the benchmark series
+}
+
+// Align buckets each series by step and keeps only the buckets every series
+// has, so returns compare like with like. The last observation in a bucket wins.
+func Align(series map[string][]Point, step time.Duration) ([]time.Time, map[string][]float64) {
+	buckets := make(map[string]map[time.Time]float64, len(series))
+	counts := make(map[time.Time]int)
+	for name, points := range series {
+		b := make(map[time.Time]float64, len(points))
+		for _, p := range points {
+			t := p.Time.UTC().Truncate(step)
+			if _, seen := b[t]; !seen {
+				counts[t]++
+			}
+			b[t] = p.Value
+		}
+		buckets[name] = b
+	}
+
+	var times []time.Time
+	for t, n := range counts {
+		if n == len(series) {
+			times = append(times, t)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	values := make(map[string][]float64, len(series))
+	for name, b := range buckets {
+		v := make([]float64, len(times))
+		for i, t := range times {
+			v[i] = b[t]
+		}
+		values[name] = v
+	}
+	return times, values
+}
+
+// Coverage returns the share of the longest series' time span that each
+// series covers, so callers can drop short histories before Align truncates
+// every series to them
+func Coverage(series map[string][]Point) map[string]float64 {
+	spans := make(map[string]time.Duration, len(series))
+	var longest time.Duration
+	for name, points := range series {
+		if len(points) == 0 {
+			spans[name] = 0
+			continue
+		}
+		first, last := points[0].Time, points[0].Time
+		for _, p := range points[1:] {
+			if p.Time.Before(first) {
+				first = p.Time
+			}
+			if p.Time.After(last) {
+				last = p.Time
+			}
+		}
+		spans[name] = last.Sub(first)
+		longest = max(longest, spans[name])
+	}
+	coverage := make(map[string]float64, len(series))
+	for name, span := range spans {
+		coverage[name] = 1
+		if longest > 0 {
+			coverage[name] = float64(span) / float64(longest)
+		}
+	}
+	return coverage
+}
+
+// Returns converts a value series into simple period returns. Periods
+// starting from zero are skipped.
+func Returns(values []float64) []float64 {
+	var out []float64
+	for i := 1; i < len(values); i++ {
+		if values[i-1] == 0 {
+			continue
+		}
+		out = append(out, values[i]/values[i-1]-1)
+	}
+	return out
+}
+
+// Compute derives Metrics for values observed at times. benchmark must be
+// aligned with values and may be nil. riskFreeRate is an annual fraction.
+func Compute(times []time.Time, values, benchmark []float64, riskFreeRate, periodsPerYear float64) Metrics {
+	m := Metrics{Observations: len(values)}
+	if len(values) == 0 {
+		return m
+	}
+	if len(times) > 0 {
+		m.Start, m.End = times[0], times[len(times)-1]
+	}
+	if values[0] != 0 {
+		m.TotalReturn = values[len(values)-1]/values[0] - 1
+	}
+	m.Drawdown = MaxDrawdown(times, values)
+
+	returns := Returns(values)
+	if len(returns) < 2 {
+		return m
+	}
+	mean, std := meanStd(returns)
+	if std > 0 {
+		vol := std * math.Sqrt(periodsPerYear)
+		m.Volatility = &vol
+	}
+
+	rf := math.Pow(1+riskFreeRate, 1/periodsPerYear) - 1
+	if std > 0 {
+		sharpe := (mean - rf) / std * math.Sqrt(periodsPerYear)
+		m.Sharpe = &sharpe
+	}
+	var downside float64
+	for _, r := range returns {
+		if d := r - rf; d < 0 {
+			downside += d * d
+		}
+	}
+	if downside > 0 {
+		sortino := (mean - rf) / math.Sqrt(downside/float64(len(returns))) * math.Sqrt(periodsPerYear)
+		m.Sortino = &sortino
+	}
+
+	if len(benchmark) == len(values) {
+		if beta, ok := Beta(returns, Returns(benchmark)); ok {
+			m.Beta = &beta
+		}
+	}
+	return m
+}
+
+// MaxDrawdown finds the largest fall from a running peak
+func MaxDrawdown(times []time.Time, values []float64) Drawdown {
+	var dd Drawdown
+	peak := 0
+	for i, v := range values {
+		if v > values[peak] {
+			peak = i
+		}
+		if values[peak] <= 0 {
+			continue
+		}
+		if fall := (values[peak] - v) / values[peak]; fall > dd.MaxDrawdown {
+			dd = Drawdown{
+				MaxDrawdown: fall,
+				Peak:        times[peak],
+				PeakValue:   values[peak],
+				Trough:      times[i],
+				TroughValue: v,
+			}
+		}
+	}
+	return dd
+}
+
+// Beta is the slope of asset returns against benchmark returns
+func Beta(asset, benchmark []float64) (float64, bool) {
+	if len(asset) != len(benchmark) || len(asset) < 2 {
+		return 0, false
+	}
+	cov, _, varB := covariance(asset, benchmark)
+	if varB == 0 {
+		return 0, false
+	}
+	return cov / varB, true
+}
+
+// Correlation is the Pearson correlation of two equally long return series
+func Correlation(a, b []float64) (float64, bool) {
+	if len(a) != len(b) || len(a) < 2 {
+		return 0, false
+	}
+	cov, varA, varB := covariance(a, b)
+	if varA == 0 || varB == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+func meanStd(xs []float64) (mean, std float64) {
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	for _, x := range xs {
+		std += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(xs)-1))
+}
+
+// covariance returns the sample covariance of a and b and their variances
+func covariance(a, b []float64) (cov, varA, varB float64) {
+	meanA, _ := meanStd(a)
+	meanB, _ := meanStd(b)
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	n := float64(len(a) - 1)
+	return cov / n, varA / n, varB / n
+}
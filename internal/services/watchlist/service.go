@@ -0,0 +1,301 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/faisal/crypto/backend/internal/config"
+	"github.com/faisal/crypto/backend/internal/models"
+	"github.com/faisal/crypto/backend/internal/repository"
+	"github.com/faisal/crypto/backend/internal/services/market"
+)
+
+// maxItems bounds a single watchlist so enriching it stays one upstream call
+const maxItems = 100
+
+var (
+	ErrNotFound      = repository.ErrNotFound
+	ErrDuplicateCoin = errors.New("coin is already on the watchlist")
+	ErrTooManyItems  = errors.New("watchlist is full")
+)
+
+type Service struct {
+	cfg           *config.Config
+	repo          repository.WatchlistRepository
+	marketService *market.Service
+}
+
+// NewService creates a watchlist service with in-memory storage (for development)
+func NewService(cfg *config.Config, marketService *market.Service) *Service {
+	return &Service{
+		cfg:           cfg,
+		repo:          repository.NewMemoryWatchlistRepository(),
+		marketService: marketService,
+	}
+}
+
+// NewServiceWithMongo creates a watchlist service backed by MongoDB
+func NewServiceWithMongo(cfg *config.Config, client *mongo.Client, marketService *market.Service) *Service {
+	return &Service{
+		cfg:           cfg,
+		repo:          repository.NewMongoWatchlistRepository(client.Database(cfg.MongoDBName)),
+		marketService: marketService,
+	}
+}
+
+// Entry is a watchlist item with its live market row. Market is nil and
+// Unavailable set when no provider knows the coin.
+type Entry struct {
+	models.WatchlistItem
+	Market      *market.CoinMarket `json:"market"`
+	Unavailable bool               `json:"unavailable"`
+}
+
+// View is a watchlist enriched with market data in one currency
+type View struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Currency  string             `json:"currency"`
+	Items     []Entry            `json:"items"`
+	CreatedAt primitive.DateTime `json:"createdAt"`
+	UpdatedAt primitive.DateTime `json:"updatedAt"`
+}
+
+func (s *Service) List(ctx context.Context, userID string) ([]models.Watchlist, error) {
+	return s.repo.ListWatchlists(ctx, userID)
+}
+
+func (s *Service) Get(ctx context.Context, id string, userID string) (*models.Watchlist, error) {
+	return s.repo.GetWatchlist(ctx, id, userID)
+}
+
+// ListWithMarkets enriches every watchlist of the user, fetching market data
+// for all of their coins at once
+func (s *Service) ListWithMarkets(ctx context.Context, userID string, currency string) ([]View, error) {
+	lists, err := s.repo.ListWatchlists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.enrich(lists, currency)
+}
+
+func (s *Service) GetWithMarkets(ctx context.Context, id string, userID string, currency string) (*View, error) {
+	list, err := s.repo.GetWatchlist(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.enrich([]models.Watchlist{*list}, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &views[0], nil
+}
+
+func (s *Service) enrich(lists []models.Watchlist, currency string) ([]View, error) {
+	currency, err := s.marketService.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, list := range lists {
+		for _, item := range list.Items {
+			ids = append(ids, item.CoinID)
+		}
+	}
+	markets := map[string]market.CoinMarket{}
+	if len(ids) > 0 {
+		if markets, err = s.marketService.GetCoinMarkets(ids, currency); err != nil {
+			return nil, err
+		}
+	}
+
+	views := make([]View, 0, len(lists))
+	for _, list := range lists {
+		view := View{
+			ID:        list.ID.Hex(),
+			Name:      list.Name,
+			Currency:  currency,
+			Items:     make([]Entry, 0, len(list.Items)),
+			CreatedAt: list.CreatedAt,
+			UpdatedAt: list.UpdatedAt,
+		}
+		for _, item := range list.Items {
+			entry := Entry{WatchlistItem: item}
+			if coin, ok := markets[item.CoinID]; ok {
+				entry.Market = &coin
+			} else {
+				entry.Unavailable = true
+			}
+			view.Items = append(view.Items, entry)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func (s *Service) Create(ctx context.Context, list models.Watchlist) (*models.Watchlist, error) {
+	now := models.ToPrimitiveDateTime(time.Now())
+	list.CreatedAt = now
+	list.UpdatedAt = now
+	if err := validateWatchlist(&list, nil); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateWatchlist(ctx, list)
+}
+
+// Update replaces a watchlist's name and items; the item order given is the
+// new display order. Coins already on the list keep their added date.
+func (s *Service) Update(ctx context.Context, list models.Watchlist) (*models.Watchlist, error) {
+	existing, err := s.repo.GetWatchlist(ctx, list.ID.Hex(), list.UserID)
+	if err != nil {
+		return nil, err
+	}
+	list.CreatedAt = existing.CreatedAt
+	list.UpdatedAt = models.ToPrimitiveDateTime(time.Now())
+	if err := validateWatchlist(&list, existing); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateWatchlist(ctx, list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string, userID string) error {
+	return s.repo.DeleteWatchlist(ctx, id, userID)
+}
+
+// AddItem inserts a coin at position, or appends it when position is nil
+func (s *Service) AddItem(ctx context.Context, id string, userID string, item models.WatchlistItem, position *int) (*models.Watchlist, error) {
+	list, err := s.repo.GetWatchlist(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	item.CoinID = normalizeCoinID(item.CoinID)
+	if item.CoinID == "" {
+		return nil, errors.New("coinId is required")
+	}
+	if indexOf(list.Items, item.CoinID) >= 0 {
+		return nil, ErrDuplicateCoin
+	}
+	if len(list.Items) >= maxItems {
+		return nil, ErrTooManyItems
+	}
+	item.Note = strings.TrimSpace(item.Note)
+	item.AddedAt = models.ToPrimitiveDateTime(time.Now())
+	list.Items = insertAt(list.Items, item, position)
+	return s.save(ctx, list)
+}
+
+// UpdateItem changes a coin's note and/or moves it to position
+func (s *Service) UpdateItem(ctx context.Context, id string, userID string, coinID string, note *string, position *int) (*models.Watchlist, error) {
+	list, err := s.repo.GetWatchlist(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	i := indexOf(list.Items, normalizeCoinID(coinID))
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	item := list.Items[i]
+	if note != nil {
+		item.Note = strings.TrimSpace(*note)
+	}
+	if position == nil {
+		list.Items[i] = item
+	} else {
+		list.Items = insertAt(append(list.Items[:i], list.Items[i+1:]...), item, position)
+	}
+	return s.save(ctx, list)
+}
+
+func (s *Service) RemoveItem(ctx context.Context, id string, userID string, coinID string) (*models.Watchlist, error) {
+	list, err := s.repo.GetWatchlist(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	i := indexOf(list.Items, normalizeCoinID(coinID))
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	list.Items = append(list.Items[:i], list.Items[i+1:]...)
+	return s.save(ctx, list)
+}
+
+func (s *Service) save(ctx context.Context, list *models.Watchlist) (*models.Watchlist, error) {
+	list.UpdatedAt = models.ToPrimitiveDateTime(time.Now())
+	if err := s.repo.UpdateWatchlist(ctx, *list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// validateWatchlist normalizes the name and items, rejecting duplicate coins.
+// Items carried over from existing keep their added date.
+func validateWatchlist(list *models.Watchlist, existing *models.Watchlist) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.UserID == "" || list.Name == "" {
+		return errors.New("invalid watchlist payload")
+	}
+	if len(list.Items) > maxItems {
+		return ErrTooManyItems
+	}
+	added := make(map[string]models.WatchlistItem)
+	if existing != nil {
+		for _, item := range existing.Items {
+			added[item.CoinID] = item
+		}
+	}
+	seen := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.CoinID = normalizeCoinID(item.CoinID)
+		if item.CoinID == "" {
+			return errors.New("coinId is required")
+		}
+		if seen[item.CoinID] {
+			return ErrDuplicateCoin
+		}
+		seen[item.CoinID] = true
+		item.Note = strings.TrimSpace(item.Note)
+		if prev, ok := added[item.CoinID]; ok {
+			item.AddedAt = prev.AddedAt
+		} else {
+			item.AddedAt = list.UpdatedAt
+		}
+	}
+	if list.Items == nil {
+		list.Items = []models.WatchlistItem{}
+	}
+	return nil
+}
+
+func normalizeCoinID(coinID string) string {
+	return strings.ToLower(strings.TrimSpace(coinID))
+}
+
+func indexOf(items []models.WatchlistItem, coinID string) int {
+	for i, item := range items {
+		if item.CoinID == coinID {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertAt places item at position, clamped to the list bounds; nil appends
+func insertAt(items []models.WatchlistItem, item models.WatchlistItem, position *int) []models.WatchlistItem {
+	i := len(items)
+	if position != nil && *position >= 0 && *position < len(items) {
+		i = *position
+	}
+	items = append(items, models.WatchlistItem{})
+	copy(items[i+1:], items[i:])
+	items[i] = item
+	return items
+}